@@ -2,19 +2,76 @@ package main
 
 type Graphics interface {
 	FillRect(rect Rectangle, r, g, b, a uint8)
+	// FillScreenRect is like FillRect but in screen coordinates, i.e. not
+	// moved by the camera.
+	FillScreenRect(rect Rectangle, r, g, b, a uint8)
 	ClearScreen(r, g, b uint8)
+	// SetClipRect restricts drawing to the given area of the screen, an empty
+	// rectangle allows drawing everywhere again.
+	SetClipRect(rect Rectangle)
+	// DrawText draws the text in screen coordinates, i.e. not moved by the
+	// camera, see text.go for the style.
+	DrawText(text string, x, y int, style TextStyle)
+	// TextSize returns the size that DrawText needs for the text.
+	TextSize(text string) (width, height int)
+	ScreenSize() (width, height int)
+	// RenderToImage creates an image of the given size and calls draw, which
+	// draws into the image instead of the screen. The image's DrawAt is in
+	// screen coordinates.
+	RenderToImage(width, height int, draw func()) Image
 }
 
+// Image is drawn in world coordinates, unless it was made by RenderToImage.
+// It is scaled like the world, i.e. by the camera's zoom and to fit the
+// virtual screen into the window.
 type Image interface {
 	DrawAt(x, y int)
+	// DrawAlphaAt draws the image partly transparent, an alpha of 0 is
+	// invisible, 255 is the same as DrawAt.
+	DrawAlphaAt(x, y int, alpha uint8)
 	Size() (width, height int)
+	// Destroy frees the image, it must not be drawn afterwards. Only images
+	// made by RenderToImage need this, the AssetLoader frees its own images.
+	Destroy()
 }
 
+// Sound is a sound effect or voice line, see audio.go.
 type Sound interface {
 	PlayOnce()
+	// Play starts the sound and returns a handle to stop it. A looped sound
+	// plays until it is stopped.
+	Play(loop bool) PlayingSound
+	// PlayAt plays the sound once, with the volume and stereo panning that
+	// make it sound like it comes from somewhere in the world, see
+	// Game.playSoundAt.
+	PlayAt(p SoundPlacement) PlayingSound
+}
+
+type PlayingSound interface {
+	Stop()
+	// FadeOut lowers the volume to 0 over the given time and then stops the
+	// sound.
+	FadeOut(milliseconds int)
+	Playing() bool
+}
+
+// Music is a track that plays in the background. Only one track plays at a
+// time, playing another one replaces it.
+type Music interface {
+	// Play starts the track, fading it in over the given time, and loops it.
+	Play(fadeInMilliseconds int)
+	// PlayOnce plays the track once, e.g. for a short stinger.
+	PlayOnce()
+	Stop()
+	FadeOut(milliseconds int)
 }
 
 type AssetLoader interface {
 	LoadImage(id string) Image
-	LoadSound(id string) Sound
+	LoadSound(id string, group SoundGroup) Sound
+	// LoadMusic returns nil if there is no track with the id.
+	LoadMusic(id string) Music
+	// SetVolumes changes the volumes of everything that is loaded, including
+	// sounds that are currently playing.
+	SetVolumes(v Volumes)
 }
@@ -0,0 +1,124 @@
+package main
+
+import "math"
+
+// The sounds are played in groups with their own volumes: sound effects and
+// voice lines. While a voice line plays, the music is ducked, i.e. made
+// quieter, so the voice can be understood. All volumes go from 0 to 100, the
+// master volume scales all the others. Sound effects can be played at a
+// position in the world, they get quieter with the distance to the camera and
+// come from its side.
+
+type SoundGroup int
+
+const (
+	EffectSound SoundGroup = iota
+	VoiceSound
+)
+
+type Volumes struct {
+	Master  int
+	Music   int
+	Effects int
+	Voice   int
+}
+
+var DefaultVolumes = Volumes{
+	Master:  100,
+	Music:   70,
+	Effects: 100,
+	Voice:   100,
+}
+
+// SoundPlacement is how loud a sound is and from which side it comes.
+type SoundPlacement struct {
+	// Volume goes from 0 to 100, it is scaled by the sound's group volume
+	Volume int
+	// Pan goes from -100, only the left speaker, to 100, only the right
+	Pan int
+}
+
+// centered is for sounds that do not come from a place in the world
+var centered = SoundPlacement{Volume: 100}
+
+const (
+	// sounds closer than soundFullVolumeDistance to the camera center play at
+	// full volume, further away they get quieter until soundMinVolume at
+	// soundFarDistance, they never go silent so far away events are heard
+	soundFullVolumeDistance = 400
+	soundFarDistance        = 4000
+	soundMinVolume          = 15
+	// soundPanDistance is how far to the side a sound is when it only comes
+	// out of one speaker
+	soundPanDistance = 1200
+)
+
+// placeSound computes how a sound at the world position sounds to the
+// camera.
+func placeSound(soundX, soundY, cameraX, cameraY int) SoundPlacement {
+	dx, dy := float64(soundX-cameraX), float64(soundY-cameraY)
+	distance := int(math.Hypot(dx, dy))
+	volume := 100
+	if distance > soundFullVolumeDistance {
+		far := clamp(distance, soundFullVolumeDistance, soundFarDistance) -
+			soundFullVolumeDistance
+		volume = 100 - far*(100-soundMinVolume)/
+			(soundFarDistance-soundFullVolumeDistance)
+	}
+	return SoundPlacement{
+		Volume: volume,
+		Pan:    clamp(int(dx)*100/soundPanDistance, -100, 100),
+	}
+}
+
+// speakers returns the left and right speakers' volumes, from 0 to 100.
+func (p SoundPlacement) speakers() (left, right int) {
+	left, right = 100, 100
+	if p.Pan > 0 {
+		left = 100 - p.Pan
+	} else {
+		right = 100 + p.Pan
+	}
+	return left, right
+}
+
+// playSoundAt plays the sound as if it came from the given point in the
+// world, relative to the camera.
+func (g *Game) playSoundAt(sound Sound, x, y int) {
+	cx, cy := g.camera.Center()
+	sound.PlayAt(placeSound(x, y, cx, cy))
+}
+
+// duckedMusicPercent is how loud the music is while a voice line plays
+const duckedMusicPercent = 35
+
+// volumeStep is how much the volume changes in the options menu
+const volumeStep = 10
+
+// soundVolume is the volume that sounds of the group are played with.
+func (v Volumes) soundVolume(group SoundGroup) int {
+	if group == VoiceSound {
+		return v.Master * v.Voice / 100
+	}
+	return v.Master * v.Effects / 100
+}
+
+// musicVolume is the volume that the music is played with.
+func (v Volumes) musicVolume(ducked bool) int {
+	volume := v.Master * v.Music / 100
+	if ducked {
+		volume = volume * duckedMusicPercent / 100
+	}
+	return volume
+}
+
+// SetVolumes changes the volumes of all sounds and music.
+func (g *Game) SetVolumes(v Volumes) {
+	g.volumes = v
+	g.assets.SetVolumes(v)
+}
+
+// Volumes returns the current volumes.
+func (g *Game) Volumes() Volumes {
+	return g.volumes
+}
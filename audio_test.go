@@ -0,0 +1,81 @@
+package main
+
+import "testing"
+
+// recordEvents turns on recording the sounds, music and images of the
+// headless game.
+func recordEvents(g *Game) *headlessAssetLoader {
+	loader := g.assets.(*headlessAssetLoader)
+	loader.recording = true
+	return loader
+}
+
+func TestSoundsArePlacedAroundTheCamera(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	loader := recordEvents(g)
+	g.camera.CenterAround(1000, 500)
+	cx, cy := g.camera.Center()
+	loader.soundEvents = nil
+
+	cases := []struct {
+		x, y int
+		want SoundPlacement
+	}{
+		{cx, cy, SoundPlacement{Volume: 100, Pan: 0}},
+		{cx + 100, cy, SoundPlacement{Volume: 100, Pan: 8}},
+		{cx, cy - 300, SoundPlacement{Volume: 100, Pan: 0}},
+		{cx + 2200, cy, SoundPlacement{Volume: 100 - 1800*85/3600, Pan: 100}},
+		{cx - 600, cy, SoundPlacement{Volume: 100 - 200*85/3600, Pan: -50}},
+		{cx - 5000, cy, SoundPlacement{Volume: soundMinVolume, Pan: -100}},
+		{cx, cy + 9000, SoundPlacement{Volume: soundMinVolume, Pan: 0}},
+	}
+	for _, c := range cases {
+		g.playSoundAt(g.fallingSound, c.x, c.y)
+	}
+
+	if len(loader.soundEvents) != len(cases) {
+		t.Fatalf("want %d sounds but have %d", len(cases), len(loader.soundEvents))
+	}
+	for i, c := range cases {
+		e := loader.soundEvents[i]
+		if e.id != "fall" || e.group != EffectSound || e.loop {
+			t.Errorf("sound %d: have %+v", i, e)
+		}
+		if e.placement != c.want {
+			t.Errorf("sound at %d,%d: want %+v but have %+v",
+				c.x-cx, c.y-cy, c.want, e.placement)
+		}
+	}
+}
+
+func TestPanningSetsTheSpeakerVolumes(t *testing.T) {
+	for _, c := range []struct {
+		pan         int
+		left, right int
+	}{
+		{0, 100, 100},
+		{40, 60, 100},
+		{-40, 100, 60},
+		{100, 0, 100},
+		{-100, 100, 0},
+	} {
+		left, right := SoundPlacement{Volume: 100, Pan: c.pan}.speakers()
+		if left != c.left || right != c.right {
+			t.Errorf("pan %d: want %d,%d but have %d,%d",
+				c.pan, c.left, c.right, left, right)
+		}
+	}
+}
+
+func TestHeadlessGamesOnlyRecordWhenAsked(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	loader := g.assets.(*headlessAssetLoader)
+	g.fallingSound.PlayOnce()
+	g.StartLevel(&level1)
+	for i := 0; i < 100; i++ {
+		g.Update()
+	}
+	if len(loader.soundEvents)+len(loader.musicEvents)+len(loader.loadedImages) > 0 {
+		t.Error("the headless game recorded events without being asked to")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+// The camera controller decides where the camera looks while following a
+// character. Instead of keeping the character exactly in the center, the
+// camera only moves once the character leaves a dead zone around the focus
+// point and then eases towards it. It looks ahead in the direction that the
+// character runs and follows jumps more slowly than falls so it does not bob
+// up and down with every jump. Shake impulses, e.g. for a hard landing, shake
+// the camera for a few frames. The camera itself still keeps the view in the
+// level's bounds.
+
+type CameraParams struct {
+	// the focus point only moves if the character leaves the dead zone
+	// around it
+	DeadZoneW, DeadZoneH int
+	// LookAhead is how far the camera looks ahead of the character in the
+	// direction that it runs, LookAheadSpeed is how many pixels per frame
+	// the look-ahead changes when the character turns around
+	LookAhead      int
+	LookAheadSpeed int
+	// the focus point moves 1/FollowDivisor of the way to where it should be
+	// every frame, while the character is in the air, it moves vertically
+	// by 1/JumpFollowDivisor, a bigger divisor is slower
+	FollowDivisor     int
+	JumpFollowDivisor int
+}
+
+var DefaultCameraParams = CameraParams{
+	DeadZoneW:         80,
+	DeadZoneH:         120,
+	LookAhead:         150,
+	LookAheadSpeed:    4,
+	FollowDivisor:     6,
+	JumpFollowDivisor: 20,
+}
+
+const (
+	// hardLandingSpeedY is the falling speed from which a landing shakes the
+	// camera, it is more than a jump on flat ground
+	hardLandingSpeedY = 30
+	hardLandingShake  = 6
+	deathShake        = 14
+	shakeDuration     = 20
+)
+
+type cameraController struct {
+	params CameraParams
+	// x and y are the focus point that the camera looks at, without the
+	// shake
+	x, y      int
+	lookAhead int
+
+	shakeStrength  int
+	shakeFrames    int
+	shakeCountDown int
+	// shakeSeed is the state of the random numbers for the shake offsets
+	shakeSeed uint32
+}
+
+func newCameraController(params CameraParams) *cameraController {
+	return &cameraController{params: params, shakeSeed: 1}
+}
+
+// reset makes the camera look at the point right away, without easing
+// towards it, and stops the shake.
+func (c *cameraController) reset(x, y int) {
+	c.x, c.y = x, y
+	c.lookAhead = 0
+	c.shakeCountDown = 0
+}
+
+// follow moves the focus point for one frame of following the character and
+// returns where the camera should look, including the shake.
+func (c *cameraController) follow(char *Character) (x, y int) {
+	p := c.params
+	if char.SpeedX > 0 {
+		c.lookAhead = moveTowards(c.lookAhead, p.LookAhead, p.LookAheadSpeed)
+	} else if char.SpeedX < 0 {
+		c.lookAhead = moveTowards(c.lookAhead, -p.LookAhead, p.LookAheadSpeed)
+	}
+
+	targetX, targetY := char.Position.Center()
+	targetX += c.lookAhead
+	goalX := outsideDeadZone(c.x, targetX, p.DeadZoneW/2)
+	goalY := outsideDeadZone(c.y, targetY, p.DeadZoneH/2)
+	c.x = easeTowards(c.x, goalX, p.FollowDivisor)
+	// falling is followed quickly so the player sees where she lands
+	if char.InAir && goalY < c.y {
+		c.y = easeTowards(c.y, goalY, p.JumpFollowDivisor)
+	} else {
+		c.y = easeTowards(c.y, goalY, p.FollowDivisor)
+	}
+
+	dx, dy := c.shakeOffset()
+	return c.x + dx, c.y + dy
+}
+
+// shake starts shaking the camera, the strength is the maximum offset in
+// pixels which goes down to 0 over the given number of frames. A weaker shake
+// does not replace a stronger one.
+func (c *cameraController) shake(strength, frames int) {
+	if c.shakeCountDown > 0 && c.currentShake() > strength {
+		return
+	}
+	c.shakeStrength = strength
+	c.shakeCountDown = frames
+	c.shakeFrames = frames
+}
+
+func (c *cameraController) currentShake() int {
+	return c.shakeStrength * c.shakeCountDown / c.shakeFrames
+}
+
+func (c *cameraController) shakeOffset() (dx, dy int) {
+	if c.shakeCountDown <= 0 {
+		return 0, 0
+	}
+	strength := c.currentShake()
+	c.shakeCountDown--
+	if strength == 0 {
+		return 0, 0
+	}
+	return c.random(2*strength+1) - strength, c.random(2*strength+1) - strength
+}
+
+// random returns a number in [0..n), see
+// https://en.wikipedia.org/wiki/Xorshift
+func (c *cameraController) random(n int) int {
+	c.shakeSeed ^= c.shakeSeed << 13
+	c.shakeSeed ^= c.shakeSeed >> 17
+	c.shakeSeed ^= c.shakeSeed << 5
+	return int(c.shakeSeed % uint32(n))
+}
+
+// outsideDeadZone returns the closest point to focus that has target within
+// the given distance.
+func outsideDeadZone(focus, target, halfZone int) int {
+	if target > focus+halfZone {
+		return target - halfZone
+	}
+	if target < focus-halfZone {
+		return target + halfZone
+	}
+	return focus
+}
+
+// easeTowards goes 1/divisor of the way from a to b, at least one pixel.
+func easeTowards(a, b, divisor int) int {
+	step := (b - a) / divisor
+	if step == 0 && a < b {
+		step = 1
+	}
+	if step == 0 && a > b {
+		step = -1
+	}
+	return a + step
+}
+
+// moveTowards goes from a towards b by at most step.
+func moveTowards(a, b, step int) int {
+	if a < b {
+		return clamp(a+step, a, b)
+	}
+	return clamp(a-step, b, a)
+}
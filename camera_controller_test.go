@@ -0,0 +1,102 @@
+package main
+
+import "testing"
+
+func TestCameraFollowsOutsideTheDeadZone(t *testing.T) {
+	// without look-ahead and easing, the focus point jumps to the edge of the
+	// dead zone, which is 40 pixels wide and 60 high each way
+	params := DefaultCameraParams
+	params.LookAhead = 0
+	params.FollowDivisor = 1
+	params.JumpFollowDivisor = 1
+	for _, c := range []struct {
+		x, y         int
+		wantX, wantY int
+	}{
+		{0, 0, 0, 0},
+		{40, 60, 0, 0},
+		{-40, -60, 0, 0},
+		{41, 0, 1, 0},
+		{100, 0, 60, 0},
+		{-100, 0, -60, 0},
+		{0, 200, 0, 140},
+		{0, -200, 0, -140},
+		{500, -500, 460, -440},
+	} {
+		camera := newCameraController(params)
+		camera.reset(0, 0)
+		char := &Character{Position: Rectangle{c.x, c.y, 0, 0}}
+		x, y := camera.follow(char)
+		if x != c.wantX || y != c.wantY {
+			t.Errorf("character at %d,%d: want the camera at %d,%d but have %d,%d",
+				c.x, c.y, c.wantX, c.wantY, x, y)
+		}
+	}
+}
+
+func TestCameraEasesTowardsTheCharacter(t *testing.T) {
+	params := DefaultCameraParams
+	params.LookAhead = 0
+	camera := newCameraController(params)
+	camera.reset(0, 0)
+	char := &Character{Position: Rectangle{640, 0, 0, 0}}
+	// 1/6 of the way to the edge of the dead zone at 600
+	if x, _ := camera.follow(char); x != 100 {
+		t.Errorf("want the camera at 100 but have %d", x)
+	}
+	// the last pixels are not left out
+	camera.reset(599, 0)
+	if x, _ := camera.follow(char); x != 600 {
+		t.Errorf("want the camera at 600 but have %d", x)
+	}
+	for i := 0; i < 100; i++ {
+		camera.follow(char)
+	}
+	if x, _ := camera.follow(char); x != 600 {
+		t.Errorf("want the camera to stop at 600 but it is at %d", x)
+	}
+}
+
+func TestCameraFollowsJumpsSlowerThanFalls(t *testing.T) {
+	up := &Character{Position: Rectangle{0, -1060, 0, 0}, InAir: true}
+	down := &Character{Position: Rectangle{0, 1060, 0, 0}, InAir: true}
+	camera := newCameraController(DefaultCameraParams)
+
+	camera.reset(0, 0)
+	if _, y := camera.follow(up); y != -1000/20 {
+		t.Errorf("jumping: want the camera at %d but have %d", -1000/20, y)
+	}
+	camera.reset(0, 0)
+	if _, y := camera.follow(down); y != 1000/6 {
+		t.Errorf("falling: want the camera at %d but have %d", 1000/6, y)
+	}
+}
+
+func TestCameraLookAheadIsClamped(t *testing.T) {
+	camera := newCameraController(DefaultCameraParams)
+	camera.reset(0, 0)
+	char := &Character{SpeedX: 5}
+	for _, step := range []struct {
+		frames int
+		speedX int
+		want   int
+	}{
+		{1, 5, 4},
+		{10, 5, 44},
+		{100, 5, 150},
+		{1000, 5, 150},
+		// standing still keeps looking where the character ran
+		{10, 0, 150},
+		{1, -5, 146},
+		{1000, -5, -150},
+	} {
+		char.SpeedX = step.speedX
+		for i := 0; i < step.frames; i++ {
+			camera.follow(char)
+		}
+		if camera.lookAhead != step.want {
+			t.Errorf("after %d frames at speed %d: want look-ahead %d but have %d",
+				step.frames, step.speedX, step.want, camera.lookAhead)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+// A camera path is a scripted camera movement, e.g. the flyover that shows a
+// level before the race. It is a list of keyframes, the camera moves from one
+// keyframe to the next and then stays there for the keyframe's hold time.
+// Keyframes can look at fixed points or at targets that are only known during
+// the race, like the winner, which the camera keeps following.
+
+type Easing int
+
+const (
+	Linear Easing = iota
+	// EaseIn starts slowly and speeds up
+	EaseIn
+	// EaseOut starts fast and slows down at the end
+	EaseOut
+	EaseInOut
+)
+
+// apply maps the time t, from 0 to 1, to how far the camera moved, also from 0
+// to 1.
+func (e Easing) apply(t float64) float64 {
+	switch e {
+	case EaseIn:
+		return t * t
+	case EaseOut:
+		return 1 - (1-t)*(1-t)
+	case EaseInOut:
+		return t * t * (3 - 2*t)
+	}
+	return t
+}
+
+type CameraTarget int
+
+const (
+	// FixedPoint looks at the keyframe's Position
+	FixedPoint CameraTarget = iota
+	// PlayerTarget looks at the player that the camera usually follows
+	PlayerTarget
+	// WinnerTarget looks at the racer who won the race
+	WinnerTarget
+	// GoalTarget looks at the center of the level's goal
+	GoalTarget
+)
+
+type CameraKeyframe struct {
+	Target   CameraTarget
+	Position Point
+	// MoveFrames is how long the camera takes to move from the last keyframe
+	// to this one, the first keyframe moves from where the camera was
+	MoveFrames int
+	Easing     Easing
+	// Hold is the number of frames that the camera stays at the keyframe
+	Hold int
+}
+
+type CameraPath []CameraKeyframe
+
+// levelFlyover shows where the race goes, it starts at the goal and flies
+// back to the players.
+var levelFlyover = CameraPath{
+	{Target: GoalTarget, Hold: 60},
+	{Target: PlayerTarget, MoveFrames: 180, Easing: EaseInOut, Hold: 20},
+}
+
+// winnerShot pans over to the winner, who cheers, after the player lost the
+// race.
+var winnerShot = CameraPath{
+	{Target: WinnerTarget, MoveFrames: 40, Easing: EaseOut, Hold: 60},
+}
+
+// cameraPathPlayer plays a camera path, one frame per update.
+type cameraPathPlayer struct {
+	path CameraPath
+	// keyframe is the keyframe that the camera moves to or holds at, frame
+	// counts the frames since leaving the last keyframe
+	keyframe int
+	frame    int
+	// from is where the camera started moving to the keyframe
+	from Point
+}
+
+func (p *cameraPathPlayer) playing() bool {
+	return p.keyframe < len(p.path)
+}
+
+func (p *cameraPathPlayer) stop() {
+	p.keyframe = len(p.path)
+}
+
+// startCameraPath moves the camera along the path, starting from where it
+// looks now.
+func (g *Game) startCameraPath(path CameraPath) {
+	x, y := g.camera.Center()
+	g.cameraPath = cameraPathPlayer{path: path, from: Point{x, y}}
+}
+
+// updateCameraPath moves the camera for one frame along the path.
+func (g *Game) updateCameraPath() {
+	p := &g.cameraPath
+	if !p.playing() {
+		return
+	}
+	k := p.path[p.keyframe]
+	target := g.cameraTarget(k)
+	x, y := target.X, target.Y
+	// the last frame of the move reaches the target, even without a hold
+	if p.frame < k.MoveFrames {
+		t := k.Easing.apply(float64(p.frame+1) / float64(k.MoveFrames))
+		x = p.from.X + int(t*float64(target.X-p.from.X))
+		y = p.from.Y + int(t*float64(target.Y-p.from.Y))
+	}
+	g.camera.CenterAround(x, y)
+
+	p.frame++
+	if p.frame >= k.MoveFrames+k.Hold {
+		p.keyframe++
+		p.frame = 0
+		p.from = target
+	}
+}
+
+func (g *Game) cameraTarget(k CameraKeyframe) Point {
+	var x, y int
+	switch k.Target {
+	case PlayerTarget:
+		x, y = g.racers[g.primaryCharIndex].character.Position.Center()
+	case WinnerTarget:
+		x, y = g.racers[g.winnerIndex].character.Position.Center()
+	case GoalTarget:
+		x, y = g.goalBounds.Center()
+	default:
+		return k.Position
+	}
+	return Point{x, y}
+}
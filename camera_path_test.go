@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestEasings(t *testing.T) {
+	for _, c := range []struct {
+		easing Easing
+		// want is where the camera is at the start, half-way and the end
+		want [3]float64
+	}{
+		{Linear, [3]float64{0, 0.5, 1}},
+		{EaseIn, [3]float64{0, 0.25, 1}},
+		{EaseOut, [3]float64{0, 0.75, 1}},
+		{EaseInOut, [3]float64{0, 0.5, 1}},
+	} {
+		for i, time := range []float64{0, 0.5, 1} {
+			if have := c.easing.apply(time); have != c.want[i] {
+				t.Errorf("easing %d at %v: want %v but have %v",
+					c.easing, time, c.want[i], have)
+			}
+		}
+		// the camera never moves backwards
+		last := 0.0
+		for i := 1; i <= 100; i++ {
+			moved := c.easing.apply(float64(i) / 100)
+			if moved < last {
+				t.Errorf("easing %d goes back at %v", c.easing, float64(i)/100)
+			}
+			last = moved
+		}
+	}
+	// ease-in-out is slow at both ends
+	if EaseInOut.apply(0.1) >= 0.1 || EaseInOut.apply(0.9) <= 0.9 {
+		t.Error("ease-in-out is not slow at the ends")
+	}
+}
+
+func TestCameraPathEndpoints(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	// a camera without bounds so the path can go anywhere
+	g.camera = newWindowCamera(VirtualScreenW, VirtualScreenH)
+	g.camera.CenterAround(0, 0)
+	g.startCameraPath(CameraPath{
+		{Position: Point{1000, 400}, MoveFrames: 10, Hold: 5},
+		{Position: Point{2000, 400}, MoveFrames: 4, Easing: EaseIn},
+	})
+
+	var centers []Point
+	for g.cameraPath.playing() && len(centers) < 100 {
+		g.updateCameraPath()
+		x, y := g.camera.Center()
+		centers = append(centers, Point{x, y})
+	}
+	if len(centers) != 10+5+4 {
+		t.Fatalf("want the path to take %d frames but it took %d",
+			10+5+4, len(centers))
+	}
+	for _, c := range []struct {
+		frame int
+		want  Point
+	}{
+		// the path starts from where the camera was
+		{0, Point{100, 40}},
+		{4, Point{500, 200}},
+		// the keyframe is reached on the last frame of the move and held
+		{9, Point{1000, 400}},
+		{14, Point{1000, 400}},
+		// the next move starts from the last keyframe and eases in
+		{15, Point{1062, 400}},
+		{16, Point{1250, 400}},
+		{17, Point{1562, 400}},
+		// the path ends at the last keyframe, which has no hold
+		{18, Point{2000, 400}},
+	} {
+		if centers[c.frame] != c.want {
+			t.Errorf("frame %d: want the camera at %v but have %v",
+				c.frame, c.want, centers[c.frame])
+		}
+	}
+}
@@ -135,6 +135,11 @@ func (c *Character) SetBottomCenterTo(x, y int) {
 }
 
 func (c *Character) Render() {
+	c.RenderAlpha(255)
+}
+
+// RenderAlpha draws the character partly transparent, see Image.DrawAlphaAt.
+func (c *Character) RenderAlpha(alpha uint8) {
 	var frame Image
 	if c.InAir {
 		frame = c.jumpFrames[c.Direction]
@@ -147,9 +152,10 @@ func (c *Character) Render() {
 	// the position is that of the collision rectangle, the image does not have
 	// the same size as the collision rectangle so it must be offset relative
 	// to the collision rectangle's top-left corner for drawing
-	frame.DrawAt(
+	frame.DrawAlphaAt(
 		c.Position.X-c.collisionRect.X,
 		c.Position.Y-c.collisionRect.Y,
+		alpha,
 	)
 }
 
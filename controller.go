@@ -0,0 +1,47 @@
+package main
+
+// An InputSource produces the InputEvents for a character, they are all fed
+// to Game.HandleInput. Controllers, the keyboard (see keyboard.go) and
+// gamepads (see gamepad.go) are input sources.
+type InputSource interface {
+	// NextInputs is called once every frame. The returned events are applied
+	// to the character with the given index in this frame.
+	NextInputs(g *Game, charIndex, frame int) []InputEvent
+}
+
+// A Controller decides the inputs for a character that is not controlled by
+// the player. Its NextInputs is called during the race, before the
+// characters are moved. The frame is counted from the character's start,
+// which can be later than the start of the race, see difficulty.go.
+type Controller interface {
+	InputSource
+	// Reset is called whenever the level starts over.
+	Reset()
+}
+
+// replayController plays back recorded inputs, see input_recording.go
+type replayController struct {
+	records []inputRecord
+	next    int
+}
+
+func newReplayController(records []inputRecord) *replayController {
+	return &replayController{records: records}
+}
+
+func (c *replayController) NextInputs(g *Game, charIndex, frame int) []InputEvent {
+	var events []InputEvent
+	for c.next < len(c.records) && c.records[c.next].frame <= frame {
+		event := c.records[c.next].event
+		if event.Action != QuitGame {
+			event.CharacterIndex = charIndex
+			events = append(events, event)
+		}
+		c.next++
+	}
+	return events
+}
+
+func (c *replayController) Reset() {
+	c.next = 0
+}
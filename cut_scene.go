@@ -0,0 +1,180 @@
+package main
+
+// A cut-scene is a script that is played in the PlayingCutScene state. The
+// commands run one after the other, without time passing, until a wait command
+// pauses the script for a number of frames. Images, animations and subtitles
+// stay on screen until the next command replaces them, so e.g. a sound can
+// play while an animation runs. The player can skip a cut-scene with Confirm.
+//
+// The scripts are text files in rsc, like rsc/intro_scene.txt, that
+// rsc/make_assets.go puts into the resources. cut_scene_script.go describes
+// their format and turns them into the commands below.
+
+type CutSceneCommandType int
+
+const (
+	ShowImage CutSceneCommandType = iota
+	AnimateImages
+	PlaySound
+	Wait
+	Subtitle
+)
+
+type CutSceneCommand struct {
+	Type CutSceneCommandType
+	// Images are the image to show or the frames of the animation
+	Images []string
+	// Frames is how long to wait, how long each frame of an animation is
+	// shown or how long a subtitle stays on screen
+	Frames int
+	Sound  string
+	Group  SoundGroup
+	Text   string
+}
+
+type CutScene struct {
+	// Music is the id of the track that plays during the cut-scene
+	Music    string
+	Commands []CutSceneCommand
+}
+
+func showImage(id string) CutSceneCommand {
+	return CutSceneCommand{Type: ShowImage, Images: []string{id}, Frames: 1}
+}
+
+// animate shows the images one after the other, each for the given number of
+// frames, and starts over after the last one.
+func animate(framesPerImage int, ids ...string) CutSceneCommand {
+	return CutSceneCommand{Type: AnimateImages, Images: ids, Frames: framesPerImage}
+}
+
+func playSound(id string) CutSceneCommand {
+	return CutSceneCommand{Type: PlaySound, Sound: id, Group: EffectSound}
+}
+
+func playVoice(id string) CutSceneCommand {
+	return CutSceneCommand{Type: PlaySound, Sound: id, Group: VoiceSound}
+}
+
+func wait(frames int) CutSceneCommand {
+	return CutSceneCommand{Type: Wait, Frames: frames}
+}
+
+// subtitle shows the text for the given number of frames, an empty text
+// removes the current subtitle.
+func subtitle(text string, frames int) CutSceneCommand {
+	return CutSceneCommand{Type: Subtitle, Text: text, Frames: frames}
+}
+
+const (
+	// the camera looks here while a cut-scene's images are drawn
+	cutSceneX = 1000
+	cutSceneY = 0
+)
+
+type cutScenePlayer struct {
+	scene *CutScene
+	// next is the index of the next command to run
+	next          int
+	waitCountDown int
+	// frame counts the frames since the start of the cut-scene
+	frame int
+	// images are the frames of the current animation, a single image is
+	// shown without animation
+	images            []Image
+	framesPerImage    int
+	animationStart    int
+	subtitle          string
+	subtitleCountDown int
+	// done is called when the cut-scene ends or is skipped
+	done func()
+}
+
+// playCutScene goes into the PlayingCutScene state and calls done when the
+// cut-scene is over. The commands before the first wait run right away, so a
+// wait(100) at the start makes the next command run in the 100th update.
+func (g *Game) playCutScene(scene *CutScene, done func()) {
+	g.cutScene = cutScenePlayer{scene: scene, done: done}
+	g.state = PlayingCutScene
+	g.runCutSceneCommands()
+}
+
+func (g *Game) updateCutScene() {
+	p := &g.cutScene
+	p.frame++
+	if p.subtitleCountDown > 0 {
+		p.subtitleCountDown--
+		if p.subtitleCountDown == 0 {
+			p.subtitle = ""
+		}
+	}
+	if p.waitCountDown > 0 {
+		p.waitCountDown--
+		if p.waitCountDown > 0 {
+			return
+		}
+	}
+
+	g.runCutSceneCommands()
+}
+
+// runCutSceneCommands runs commands until the next wait and ends the
+// cut-scene after the last one.
+func (g *Game) runCutSceneCommands() {
+	p := &g.cutScene
+	for p.next < len(p.scene.Commands) && p.waitCountDown == 0 {
+		g.runCutSceneCommand(p.scene.Commands[p.next])
+		p.next++
+	}
+	if p.next >= len(p.scene.Commands) && p.waitCountDown == 0 {
+		g.endCutScene()
+	}
+}
+
+func (g *Game) runCutSceneCommand(c CutSceneCommand) {
+	p := &g.cutScene
+	switch c.Type {
+	case ShowImage, AnimateImages:
+		p.images = p.images[:0]
+		for _, id := range c.Images {
+			p.images = append(p.images, g.assets.LoadImage(id))
+		}
+		p.framesPerImage = c.Frames
+		p.animationStart = p.frame
+	case PlaySound:
+		g.assets.LoadSound(c.Sound, c.Group).PlayOnce()
+	case Wait:
+		p.waitCountDown = c.Frames
+	case Subtitle:
+		p.subtitle = c.Text
+		p.subtitleCountDown = c.Frames
+	}
+}
+
+// endCutScene stops the cut-scene, a sound that is still playing goes on.
+func (g *Game) endCutScene() {
+	done := g.cutScene.done
+	g.cutScene = cutScenePlayer{}
+	done()
+}
+
+func (g *Game) renderCutScene() {
+	p := &g.cutScene
+	g.camera.CenterAround(cutSceneX, cutSceneY)
+	g.graphics.ClearScreen(0, 0, 0)
+
+	if len(p.images) > 0 {
+		index := 0
+		if p.framesPerImage > 0 {
+			index = (p.frame - p.animationStart) / p.framesPerImage % len(p.images)
+		}
+		img := p.images[index]
+		w, h := img.Size()
+		img.DrawAt(cutSceneX-w/2, cutSceneY-h/2)
+	}
+
+	if p.subtitle != "" {
+		w, h := g.graphics.ScreenSize()
+		g.graphics.DrawText(p.subtitle, w/2, h-h/8, centeredText)
+	}
+}
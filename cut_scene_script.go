@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gophergala2016/gophette/resource"
+	"strconv"
+	"text/scanner"
+)
+
+// A cut-scene script has one command per line, a command is a name followed
+// by its arguments. Texts and ids are in double quotes, frame counts are plain
+// numbers and // starts a comment. These are the commands:
+//
+//	music "intro music"              the track that plays, at most once
+//	image "intro pc 1"               shows an image
+//	animate 10 "pc 1" "pc 2"         shows each image for 10 frames, in a loop
+//	sound "lose"                     plays a sound effect
+//	voice "instructions"             plays a voice line
+//	subtitle "Hello there!" 240      shows the text for 240 frames
+//	wait 100                         waits 100 frames before the next command
+
+var (
+	introScene = loadCutScene("intro scene")
+	endScene   = loadCutScene("end scene")
+)
+
+// loadCutScene parses the script from the resources, the scripts are part of
+// the game so a broken one is a bug.
+func loadCutScene(id string) CutScene {
+	scene, err := parseCutScene(resource.Resources[id])
+	if err != nil {
+		panic(fmt.Sprintf("cut-scene %q: %v", id, err))
+	}
+	return scene
+}
+
+func parseCutScene(script []byte) (CutScene, error) {
+	var scene CutScene
+	lines, err := scanScript(script)
+	if err != nil {
+		return scene, err
+	}
+	for _, line := range lines {
+		if line.command == "music" {
+			if scene.Music != "" {
+				return scene, line.errorf("the music is set twice")
+			}
+			scene.Music, err = line.text(0, 1)
+		} else {
+			var c CutSceneCommand
+			c, err = line.toCommand()
+			scene.Commands = append(scene.Commands, c)
+		}
+		if err != nil {
+			return scene, err
+		}
+	}
+	if len(scene.Commands) == 0 {
+		return scene, errors.New("the script has no commands")
+	}
+	return scene, nil
+}
+
+// scriptLine is a command with its arguments, which are strings for texts and
+// ints for frame counts.
+type scriptLine struct {
+	line    int
+	command string
+	args    []interface{}
+}
+
+func (l scriptLine) toCommand() (CutSceneCommand, error) {
+	switch l.command {
+	case "image":
+		id, err := l.text(0, 1)
+		return showImage(id), err
+	case "animate":
+		if len(l.args) < 2 {
+			return CutSceneCommand{}, l.errorf(
+				"animate needs the frames per image and at least one image")
+		}
+		frames, err := l.frames(0)
+		if err != nil {
+			return CutSceneCommand{}, err
+		}
+		ids := make([]string, len(l.args)-1)
+		for i := range ids {
+			ids[i], err = l.text(i+1, len(l.args))
+			if err != nil {
+				return CutSceneCommand{}, err
+			}
+		}
+		return animate(frames, ids...), nil
+	case "sound":
+		id, err := l.text(0, 1)
+		return playSound(id), err
+	case "voice":
+		id, err := l.text(0, 1)
+		return playVoice(id), err
+	case "subtitle":
+		if len(l.args) != 2 {
+			return CutSceneCommand{}, l.errorf(
+				"subtitle needs a text and the number of frames")
+		}
+		text, err := l.text(0, 2)
+		if err != nil {
+			return CutSceneCommand{}, err
+		}
+		frames, err := l.frames(1)
+		return subtitle(text, frames), err
+	case "wait":
+		if len(l.args) != 1 {
+			return CutSceneCommand{}, l.errorf("wait needs the number of frames")
+		}
+		frames, err := l.frames(0)
+		return wait(frames), err
+	}
+	return CutSceneCommand{}, l.errorf("unknown command %q", l.command)
+}
+
+// text returns the i'th argument, which has to be a string, count is the
+// number of arguments that the command takes.
+func (l scriptLine) text(i, count int) (string, error) {
+	if len(l.args) != count {
+		return "", l.errorf("%s needs %d text argument(s) but has %d arguments",
+			l.command, count, len(l.args))
+	}
+	text, ok := l.args[i].(string)
+	if !ok {
+		return "", l.errorf("argument %d of %s must be a text in quotes",
+			i+1, l.command)
+	}
+	return text, nil
+}
+
+// frames returns the i'th argument, which has to be a frame count greater
+// than 0.
+func (l scriptLine) frames(i int) (int, error) {
+	frames, ok := l.args[i].(int)
+	if !ok || frames <= 0 {
+		return 0, l.errorf("argument %d of %s must be a number of frames",
+			i+1, l.command)
+	}
+	return frames, nil
+}
+
+func (l scriptLine) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, a...))
+}
+
+// scanScript splits the script into its lines, leaving out empty lines and
+// comments.
+func scanScript(script []byte) ([]scriptLine, error) {
+	var s scanner.Scanner
+	s.Init(bytes.NewReader(script))
+	s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanStrings |
+		scanner.ScanComments | scanner.SkipComments
+	// new lines end commands so they are not skipped as white space
+	s.Whitespace = 1<<' ' | 1<<'\t' | 1<<'\r'
+	var err error
+	s.Error = func(s *scanner.Scanner, msg string) {
+		pos := s.Position
+		if !pos.IsValid() {
+			pos = s.Pos()
+		}
+		if err == nil {
+			err = fmt.Errorf("line %d: %s", pos.Line, msg)
+		}
+	}
+
+	var lines []scriptLine
+	var line *scriptLine
+	for token := s.Scan(); token != scanner.EOF && err == nil; token = s.Scan() {
+		if token == '\n' {
+			line = nil
+			continue
+		}
+		if line == nil {
+			if token != scanner.Ident {
+				return nil, fmt.Errorf("line %d: expected a command but found %s",
+					s.Position.Line, s.TokenText())
+			}
+			lines = append(lines, scriptLine{
+				line:    s.Position.Line,
+				command: s.TokenText(),
+			})
+			line = &lines[len(lines)-1]
+			continue
+		}
+		switch token {
+		case scanner.String:
+			text, unquoteErr := strconv.Unquote(s.TokenText())
+			if unquoteErr != nil {
+				return nil, line.errorf("bad text %s", s.TokenText())
+			}
+			line.args = append(line.args, text)
+		case scanner.Int:
+			n, convErr := strconv.Atoi(s.TokenText())
+			if convErr != nil {
+				return nil, line.errorf("bad number %s", s.TokenText())
+			}
+			line.args = append(line.args, n)
+		default:
+			return nil, line.errorf("unexpected %s", s.TokenText())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
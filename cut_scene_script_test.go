@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCutScene(t *testing.T) {
+	scene, err := parseCutScene([]byte(`// a comment
+music "intro music"
+
+image "intro pc 1"  // comments can end lines
+wait 100
+animate 10 "intro pc 1" "intro pc 2"
+voice "barney intro text"
+subtitle "He says \"hi\"" 240
+sound "lose"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CutScene{
+		Music: "intro music",
+		Commands: []CutSceneCommand{
+			showImage("intro pc 1"),
+			wait(100),
+			animate(10, "intro pc 1", "intro pc 2"),
+			playVoice("barney intro text"),
+			subtitle(`He says "hi"`, 240),
+			playSound("lose"),
+		},
+	}
+	if !reflect.DeepEqual(scene, want) {
+		t.Errorf("want\n%+v\nbut have\n%+v", want, scene)
+	}
+}
+
+func TestParseCutSceneErrors(t *testing.T) {
+	for _, c := range []struct {
+		script string
+		err    string
+	}{
+		{``, "the script has no commands"},
+		{`music "intro music"`, "the script has no commands"},
+		{"wait 1\njump 5", `line 2: unknown command "jump"`},
+		{`"image"`, "line 1: expected a command"},
+		{`image intro`, "line 1: unexpected intro"},
+		{`image 5`, "line 1: argument 1 of image must be a text"},
+		{`image "a" "b"`, "line 1: image needs 1 text argument(s)"},
+		{"wait 1\n\nimage \"pc", "line 3: literal not terminated"},
+		{`wait 0`, "line 1: argument 1 of wait must be a number of frames"},
+		{`wait -5`, "line 1: unexpected -"},
+		{`wait "long"`, "argument 1 of wait must be a number of frames"},
+		{`wait`, "line 1: wait needs the number of frames"},
+		{`animate 10`, "line 1: animate needs the frames per image"},
+		{`animate "a" "b"`, "argument 1 of animate must be a number of frames"},
+		{`animate 10 "a" 5`, "argument 3 of animate must be a text"},
+		{`subtitle "hi"`, "line 1: subtitle needs a text and the number"},
+		{`subtitle 5 "hi"`, "argument 1 of subtitle must be a text"},
+		{"music \"a\"\nmusic \"b\"\nwait 1", "line 2: the music is set twice"},
+	} {
+		_, err := parseCutScene([]byte(c.script))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%q: want error %q but have %v", c.script, c.err, err)
+		}
+	}
+}
+
+func TestCutSceneScriptsParse(t *testing.T) {
+	for _, file := range []string{"rsc/intro_scene.txt", "rsc/end_scene.txt"} {
+		script, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseCutScene(script); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestIntroSceneTiming(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	loader := recordEvents(g)
+	loader.soundEvents = nil
+	loader.loadedImages = nil
+	g.startLevel(&level1, true)
+	if g.state != PlayingCutScene {
+		t.Fatalf("want the intro but the state is %v", g.state)
+	}
+
+	// these are the frames at which the intro did things before it was
+	// scripted
+	want := map[string]int{
+		"intro pc 1":        0,
+		"barney intro text": 100,
+		"intro gophette":    580,
+		"lose":              620,
+		"instructions":      700,
+	}
+	have := map[string]int{}
+	record := func(frame int) {
+		for _, e := range loader.soundEvents {
+			have[e.id] = frame
+		}
+		for _, id := range loader.loadedImages {
+			if _, ok := have[id]; !ok {
+				have[id] = frame
+			}
+		}
+		loader.soundEvents = nil
+		loader.loadedImages = nil
+	}
+	record(0)
+	frame := 0
+	for g.state == PlayingCutScene && frame < 2000 {
+		g.Update()
+		frame++
+		record(frame)
+	}
+
+	for id, frame := range want {
+		if have[id] != frame {
+			t.Errorf("%q: want frame %d but have %d", id, frame, have[id])
+		}
+	}
+	if frame != 930 {
+		t.Errorf("want the intro to end at frame 930 but it ended at %d", frame)
+	}
+	if g.state != PrePlaying {
+		t.Errorf("want the race to start after the intro but the state is %v",
+			g.state)
+	}
+}
+
+func TestConfirmSkipsCutScene(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.startLevel(&level1, true)
+	g.Update()
+	g.HandleInput(InputEvent{Confirm, true, 0})
+	if g.state != PrePlaying {
+		t.Errorf("want the race to start but the state is %v", g.state)
+	}
+	// the same press must not skip the flyover that follows the intro too
+	if !g.cameraPath.playing() {
+		t.Error("want the flyover to play after skipping the intro")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import "strings"
+
+type Difficulty int
+
+const (
+	Easy Difficulty = iota + 1
+	Normal
+	Hard
+)
+
+func (d Difficulty) String() string {
+	switch d {
+	case Easy:
+		return "Easy"
+	case Normal:
+		return "Normal"
+	case Hard:
+		return "Hard"
+	default:
+		return "unknown difficulty"
+	}
+}
+
+// parseDifficulty returns the difficulty with the given name, ignoring case.
+func parseDifficulty(name string) (Difficulty, bool) {
+	for _, d := range []Difficulty{Easy, Normal, Hard} {
+		if strings.EqualFold(name, d.String()) {
+			return d, true
+		}
+	}
+	return Normal, false
+}
+
+// the difficulty applies to all computer controlled racers
+type difficultySettings struct {
+	// maxSpeedXChange is added to the racers' MaxSpeedX
+	maxSpeedXChange int
+	// startDelay is the number of frames that the racers wait after the start
+	// of the race
+	startDelay int
+	// with rubberBand set, a racer slows down when he is far ahead of the
+	// player and speeds up when he is far behind
+	rubberBand bool
+}
+
+func (d Difficulty) settings() difficultySettings {
+	switch d {
+	case Easy:
+		return difficultySettings{
+			maxSpeedXChange: -1,
+			startDelay:      40,
+			rubberBand:      true,
+		}
+	case Hard:
+		return difficultySettings{
+			maxSpeedXChange: 1,
+		}
+	default:
+		return difficultySettings{}
+	}
+}
+
+const (
+	// rubberBandDistance is how far (horizontally) a racer has to be ahead or
+	// behind for the rubber band to kick in
+	rubberBandDistance = 1000
+	rubberBandSpeedX   = 2
+	// minRacerSpeedX keeps slow racers moving, e.g. on Easy or when the
+	// rubber band slows them down, racerSteps also divides by the speed
+	minRacerSpeedX = 1
+)
+
+// SetDifficulty must be called before the race starts and before creating
+// path controllers since they plan with the racer's speed.
+func (g *Game) SetDifficulty(d Difficulty) {
+	g.difficulty = d
+}
+
+// racerMaxSpeedX returns a computer controlled racer's maximum speed for the
+// current frame.
+func (g *Game) racerMaxSpeedX(r *racer) int {
+	settings := g.difficulty.settings()
+	speed := r.defaultParams.MaxSpeedX + settings.maxSpeedXChange
+	if settings.rubberBand {
+		player := g.racers[g.primaryCharIndex].character
+		lead := g.goalDistanceX(player) - g.goalDistanceX(r.character)
+		if lead > rubberBandDistance {
+			speed -= rubberBandSpeedX
+		}
+		if lead < -rubberBandDistance {
+			speed += rubberBandSpeedX
+		}
+	}
+	if speed < minRacerSpeedX {
+		speed = minRacerSpeedX
+	}
+	return speed
+}
+
+// racerSteps sets a computer controlled racer's speed for the current frame
+// and returns how many times he is updated in this frame.
+func (g *Game) racerSteps(r *racer) int {
+	settings := g.difficulty.settings()
+	if g.frame <= settings.startDelay {
+		return 0
+	}
+
+	// a replay was recorded with the racer's default speed, a path controller
+	// plans with the speed for the difficulty
+	baseSpeed := r.defaultParams.MaxSpeedX + settings.maxSpeedXChange
+	if _, isReplay := r.controller.(*replayController); isReplay {
+		baseSpeed = r.defaultParams.MaxSpeedX
+	}
+	if baseSpeed < minRacerSpeedX {
+		baseSpeed = minRacerSpeedX
+	}
+	r.character.Params.MaxSpeedX = baseSpeed
+
+	// changing the racer's speed from the one he planned with (or was
+	// recorded with) would get him off his path, instead his time runs faster
+	// or slower
+	r.time += g.racerMaxSpeedX(r)
+	steps := r.time / baseSpeed
+	r.time %= baseSpeed
+	return steps
+}
+
+// goalDistanceX is the horizontal distance from the character to the goal
+func (g *Game) goalDistanceX(char *Character) int {
+	left := g.goalBounds.X - (char.Position.X + char.Position.W)
+	right := char.Position.X - (g.goalBounds.X + g.goalBounds.W)
+	if left > 0 {
+		return left
+	}
+	if right > 0 {
+		return right
+	}
+	return 0
+}
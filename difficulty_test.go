@@ -0,0 +1,125 @@
+package main
+
+import "testing"
+
+// countRacerSteps returns how many steps Barney makes in the frames after the
+// start delay, the racers stay where they are.
+func countRacerSteps(g *Game, frames int) int {
+	g.frame = g.difficulty.settings().startDelay
+	steps := 0
+	for i := 0; i < frames; i++ {
+		g.frame++
+		steps += g.racerSteps(g.racers[1])
+	}
+	return steps
+}
+
+func TestRacerStartDelay(t *testing.T) {
+	for _, c := range []struct {
+		difficulty Difficulty
+		delay      int
+	}{
+		{Easy, 40},
+		{Normal, 0},
+		{Hard, 0},
+	} {
+		g := newHeadlessGame(&level1)
+		g.SetDifficulty(c.difficulty)
+		barney := g.racers[1]
+		for g.frame = 1; g.frame <= c.delay; g.frame++ {
+			if steps := g.racerSteps(barney); steps != 0 || barney.time != 0 {
+				t.Errorf("%v: Barney made %d steps in frame %d",
+					c.difficulty, steps, g.frame)
+			}
+		}
+		// on Easy, Barney's time runs slower and he might not make a whole
+		// step in his first frame
+		if steps := g.racerSteps(barney); steps == 0 && barney.time == 0 {
+			t.Errorf("%v: Barney did not start after frame %d",
+				c.difficulty, c.delay)
+		}
+	}
+}
+
+func TestRacerStepsPerFrame(t *testing.T) {
+	// a replay was recorded with Barney's default speed, so on Hard he has
+	// to make more steps, a path controller runs faster instead
+	speed := NewBarney(&headlessAssetLoader{}).Params.MaxSpeedX
+	for _, c := range []struct {
+		difficulty     Difficulty
+		path           bool
+		steps          int
+		maxSpeedX      int
+		characterSpeed int
+	}{
+		{Easy, false, speed - 1, speed - 1, speed},
+		{Normal, false, speed, speed, speed},
+		{Hard, false, speed + 1, speed + 1, speed},
+		{Easy, true, speed, speed - 1, speed - 1},
+		{Normal, true, speed, speed, speed},
+		{Hard, true, speed, speed + 1, speed + 1},
+	} {
+		g := newHeadlessGame(&level1)
+		g.SetDifficulty(c.difficulty)
+		if c.path {
+			g.SetController(1, newPathController(g, 1))
+		}
+		// over speed frames, the steps are exactly the speed ratio
+		steps := countRacerSteps(g, speed)
+		if steps != c.steps {
+			t.Errorf("%v, path %v: want %d steps but have %d",
+				c.difficulty, c.path, c.steps, steps)
+		}
+		if max := g.racerMaxSpeedX(g.racers[1]); max != c.maxSpeedX {
+			t.Errorf("%v, path %v: want max speed %d but have %d",
+				c.difficulty, c.path, c.maxSpeedX, max)
+		}
+		if have := g.racers[1].character.Params.MaxSpeedX; have != c.characterSpeed {
+			t.Errorf("%v, path %v: want character speed %d but have %d",
+				c.difficulty, c.path, c.characterSpeed, have)
+		}
+	}
+}
+
+func TestRubberBand(t *testing.T) {
+	speed := NewBarney(&headlessAssetLoader{}).Params.MaxSpeedX
+	nearGoal := level1.Goal.X - 500
+	for _, c := range []struct {
+		difficulty Difficulty
+		// barneyX and playerX are the racers' positions
+		barneyX, playerX int
+		want             int
+	}{
+		{Easy, 300, 500, speed - 1},
+		{Easy, nearGoal, 500, speed - 1 - rubberBandSpeedX},
+		{Easy, 300, nearGoal, speed - 1 + rubberBandSpeedX},
+		{Normal, nearGoal, 500, speed},
+		{Normal, 300, nearGoal, speed},
+		{Hard, nearGoal, 500, speed + 1},
+		{Hard, 300, nearGoal, speed + 1},
+	} {
+		g := newHeadlessGame(&level1)
+		g.SetDifficulty(c.difficulty)
+		g.racers[0].character.Position.X = c.playerX
+		g.racers[1].character.Position.X = c.barneyX
+		if have := g.racerMaxSpeedX(g.racers[1]); have != c.want {
+			t.Errorf("%v, Barney at %d, player at %d: want speed %d but have %d",
+				c.difficulty, c.barneyX, c.playerX, c.want, have)
+		}
+	}
+}
+
+func TestSlowRacersKeepMoving(t *testing.T) {
+	for _, defaultSpeed := range []int{0, 1, 2} {
+		g := newHeadlessGame(&level1)
+		g.SetDifficulty(Easy)
+		barney := g.racers[1]
+		barney.defaultParams.MaxSpeedX = defaultSpeed
+		// Barney is far ahead, the rubber band slows him down even more
+		barney.character.Position.X = level1.Goal.X - 500
+		if steps := countRacerSteps(g, 10); steps <= 0 {
+			t.Errorf("speed %d: want Barney to move but he made %d steps",
+				defaultSpeed, steps)
+		}
+	}
+}
@@ -4,53 +4,120 @@ const (
 	PrePlayFrameDelay    = 100
 	PlayerDyingDelay     = 100
 	LosingSoundDelay     = 90
-	BarneyWinDelay       = 100
 	PlayerWinDelay       = 80
+	VersusWinDelay       = 150
+	RespawnDelay         = 60
 	WhistleSoundDuration = 35
-	IntroDuration        = 930
 )
 
 type Game struct {
+	assets   AssetLoader
 	graphics Graphics
 	camera   Camera
+	// cameraControl follows the player smoothly, see camera_controller.go
+	cameraControl *cameraController
+	// cameraPath is the scripted camera movement, e.g. the level's flyover,
+	// see camera_path.go
+	cameraPath cameraPathPlayer
+	level      *Level
+	menu       menu
+	// pausedState is the state to go back to when resuming
+	pausedState GameState
 
 	state                GameState
 	prePlayCountDown     int
 	playerDyingCountDown int
 	dieBounds            Rectangle
-	aiInputs             []inputRecord
+	difficulty           Difficulty
 	goalBounds           Rectangle
 	losingSoundCountDown int
-	barneyWinCountDown   int
 	playerWinCountDown   int
-	introCountUp         int
+	versusWinCountDown   int
+	// frame counts the frames since the start of the race
+	frame int
 
 	running          bool
-	characters       [2]*Character
-	inputStates      [2]inputState
+	racers           []*racer
+	finishOrder      []int
 	primaryCharIndex int
+	// winnerIndex is the racer that beat the player or, in versus mode, the
+	// first to reach the goal
+	winnerIndex int
+	// in versus mode, all racers controlled by players race each other,
+	// nobody loses the race by falling out of the level
+	versus bool
+
+	// runRecorder has the player's inputs for the current attempt, the
+	// fastest finished attempt per level is replayed by the ghost
+	runRecorder inputRecorder
+	bestRuns    map[string]bestRun
+	ghost       *ghost
+
+	volumes Volumes
+	// music is the track that is playing, see music.go
+	music              Music
+	musicTrack         musicTrack
+	nextMusic          Music
+	nextMusicLoops     bool
+	musicFadeCountDown int
+
+	showMinimap  bool
+	minimap      Image
+	minimapLevel *Level
+	// timesPath is the file that the best runs are saved to
+	timesPath    string
+	splitResults []SplitResult
+	leaderboard  *leaderboard
+	playerName   string
+
+	// settings are saved to settingsPath, see settings.go
+	settings     Settings
+	settingsPath string
+	skipIntro    bool
+	// keyBindings are the player's keys, secondKeyBindings are Barney's in
+	// local versus mode
+	keyBindings       *KeyBindings
+	secondKeyBindings *KeyBindings
+	// waitingForKey is set in the controls menu while the next key is bound
+	// to the selected action
+	waitingForKey bool
 
 	objects      []CollisionObject
 	imageObjects []ImageObject
 
-	winningSound         Sound
-	losingSound          Sound
-	fallingSound         Sound
-	barneyWinSound       Sound
-	whistleSound         Sound
-	barneyIntroTextSound Sound
-	introInstructions    Sound
-
-	introPC1            Image
-	introPC2            Image
-	introGophette       Image
-	currentIntroPCImage int
-	introBarneyTalking  bool
+	winningSound   Sound
+	losingSound    Sound
+	fallingSound   Sound
+	barneyWinSound Sound
+	whistleSound   Sound
+
+	introGophette Image
+	// cutScene is the script that plays in the PlayingCutScene state, see
+	// cut_scene.go
+	cutScene cutScenePlayer
 }
 
 type Camera interface {
 	CenterAround(x, y int)
 	SetBounds(Rectangle)
+	WorldToScreen(x, y int) (screenX, screenY int)
+	// Center returns the point in the world that the camera looks at.
+	Center() (x, y int)
+}
+
+// A MultiViewCamera can split the screen to follow several players.
+type MultiViewCamera interface {
+	Camera
+	// CenterViewsAround shows each point in its own view or all of them in a
+	// single view if they are close together.
+	CenterViewsAround(points []Point)
+	ViewCount() int
+	// ViewPoint returns the index of the point that the view follows, a
+	// single view follows the first point.
+	ViewPoint(i int) int
+	// SelectView makes drawing go to the view with the given index and
+	// returns the view's area on the screen.
+	SelectView(i int) (viewport Rectangle)
 }
 
 type inputState struct {
@@ -60,6 +127,19 @@ type inputState struct {
 	mustJumpThisFrame bool
 }
 
+func (s *inputState) apply(event InputEvent) {
+	if event.Action == GoLeft {
+		s.leftDown = event.Pressed
+	}
+	if event.Action == GoRight {
+		s.rightDown = event.Pressed
+	}
+	if event.Action == Jump {
+		s.mustJumpThisFrame = event.Pressed
+		s.jumpDown = event.Pressed
+	}
+}
+
 type GameState int
 
 const (
@@ -69,54 +149,97 @@ const (
 	PlayerWinning
 	PlayerRealizingLoss
 	CameraShowsBarneyWinning
-	IntroPCScene
+	PlayingCutScene
+	VersusWinning
+	TitleScreen
+	MainMenu
+	LevelSelect
+	OptionsMenu
+	Paused
+	ControlsMenu
+	LeaderboardMenu
 )
 
 func NewGame(
 	assets AssetLoader,
 	graphics Graphics,
 	cam Camera,
+	level *Level,
 	cameraFocusCharIndex int,
 ) *Game {
-	hero := NewHero(assets)
-	hero.SetBottomCenterTo(500, 537)
-	//hero.SetBottomCenterTo(8800, -800) // TODO
-	hero.Direction = RightDirectionIndex
+	game := &Game{
+		running:           true,
+		assets:            assets,
+		graphics:          graphics,
+		primaryCharIndex:  cameraFocusCharIndex,
+		camera:            cam,
+		cameraControl:     newCameraController(DefaultCameraParams),
+		difficulty:        Normal,
+		winningSound:      assets.LoadSound("win", EffectSound),
+		losingSound:       assets.LoadSound("lose", EffectSound),
+		fallingSound:      assets.LoadSound("fall", EffectSound),
+		barneyWinSound:    assets.LoadSound("barney wins", EffectSound),
+		whistleSound:      assets.LoadSound("whistle", EffectSound),
+		introGophette:     assets.LoadImage("intro gophette"),
+		runRecorder:       inputRecorder{characterIndex: cameraFocusCharIndex},
+		bestRuns:          make(map[string]bestRun),
+		keyBindings:       defaultKeyBindings(),
+		secondKeyBindings: defaultSecondPlayerBindings(),
+	}
+	game.AddRacer(NewHero(assets), nil, level.HeroStart)
+	game.AddRacer(
+		NewBarney(assets),
+		newReplayController(level.BarneyInputs),
+		level.BarneyStart,
+	)
+	game.loadLevel(level)
+	game.SetVolumes(DefaultVolumes)
+	game.state = TitleScreen
+	return game
+}
 
-	barney := NewBarney(assets)
-	//barney.SetBottomCenterTo(8500, -800)
-	barney.SetBottomCenterTo(300, 537) // TODO
-	barney.Direction = RightDirectionIndex
+// StartLevel starts the race on the given level with the intro, unless the
+// player chose to skip it.
+func (g *Game) StartLevel(level *Level) {
+	g.startLevel(level, !g.skipIntro)
+}
 
-	cameraBounds := Rectangle{200, -1399, 9150, 2100}
-	cam.SetBounds(cameraBounds)
+func (g *Game) startLevel(level *Level, intro bool) {
+	if level != g.level {
+		g.changeLevel(level)
+	}
+	g.resetLevel()
+	if intro {
+		g.playCutScene(&introScene, g.endIntro)
+	} else {
+		g.startCameraPath(level.Flyover)
+	}
+}
 
-	game := &Game{
-		running:              true,
-		graphics:             graphics,
-		characters:           [2]*Character{hero, barney},
-		primaryCharIndex:     cameraFocusCharIndex,
-		camera:               cam,
-		dieBounds:            cameraBounds.AddMargin(200),
-		aiInputs:             recordedInputs,
-		goalBounds:           Rectangle{9200, -1000, 1000, 350},
-		winningSound:         assets.LoadSound("win"),
-		losingSound:          assets.LoadSound("lose"),
-		fallingSound:         assets.LoadSound("fall"),
-		barneyWinSound:       assets.LoadSound("barney wins"),
-		whistleSound:         assets.LoadSound("whistle"),
-		barneyIntroTextSound: assets.LoadSound("barney intro text"),
-		introInstructions:    assets.LoadSound("instructions"),
-		introPC1:             assets.LoadImage("intro pc 1"),
-		introPC2:             assets.LoadImage("intro pc 2"),
-		introGophette:        assets.LoadImage("intro gophette"),
-	}
-	game.loadLevel(assets, &level1)
-	game.state = IntroPCScene
-	return game
+// changeLevel moves the racers to the new level's start, each keeps his place
+// relative to the start.
+func (g *Game) changeLevel(level *Level) {
+	for i, r := range g.racers {
+		from, to := g.level.BarneyStart, level.BarneyStart
+		if i == 0 {
+			from, to = g.level.HeroStart, level.HeroStart
+		}
+		r.spawn = Point{to.X + r.spawn.X - from.X, to.Y + r.spawn.Y - from.Y}
+		if replay, ok := r.controller.(*replayController); ok {
+			// replays belong to the level they were recorded on
+			replay.records = level.BarneyInputs
+		}
+	}
+	g.loadLevel(level)
 }
 
-func (g *Game) loadLevel(assets AssetLoader, level *Level) {
+func (g *Game) loadLevel(level *Level) {
+	g.level = level
+	g.camera.SetBounds(level.CameraBounds)
+	g.dieBounds = level.CameraBounds.AddMargin(200)
+	g.goalBounds = level.Goal
+
+	assets := g.assets
 	g.imageObjects = make([]ImageObject, len(level.Images))
 	for i := range level.Images {
 		img := &level.Images[i]
@@ -141,87 +264,116 @@ func (g *Game) loadLevel(assets AssetLoader, level *Level) {
 }
 
 func (g *Game) HandleInput(event InputEvent) {
-	recordInput(event)
-
-	inputState := &g.inputStates[event.CharacterIndex]
-
-	if event.Action == GoLeft {
-		inputState.leftDown = event.Pressed
+	if g.inMenu() && event.Action != QuitGame {
+		g.handleMenuInput(event)
+		return
 	}
-	if event.Action == GoRight {
-		inputState.rightDown = event.Pressed
+	if event.Pressed && event.CharacterIndex == g.primaryCharIndex {
+		if event.Action == Confirm && g.state == PlayingCutScene {
+			g.endCutScene()
+		} else if event.Action == Confirm && g.state == PrePlaying &&
+			g.cameraPath.playing() {
+			g.skipFlyover()
+		}
+		if event.Action == Back {
+			g.pause()
+			return
+		}
+		if event.Action == ToggleMinimap {
+			g.ToggleMinimap()
+			return
+		}
 	}
-	if event.Action == Jump {
-		inputState.mustJumpThisFrame = event.Pressed
-		inputState.jumpDown = event.Pressed
+
+	g.recordEvent(event)
+
+	if 0 <= event.CharacterIndex && event.CharacterIndex < len(g.racers) {
+		g.racers[event.CharacterIndex].input.apply(event)
 	}
 
 	if event.Action == QuitGame {
-		g.running = false
-		if recordingInput {
-			saveRecordedInputs()
-		}
+		g.quit()
 	}
 }
 
-func (g *Game) Update() {
-	if g.state == IntroPCScene {
-		g.introCountUp++
+// recordEvent keeps the input for the debug recording and for the current
+// run, both must see the same inputs to replay it.
+func (g *Game) recordEvent(event InputEvent) {
+	recordInput(g.frame, event)
+	g.runRecorder.record(g.frame, event)
+}
 
-		if g.introCountUp == 100 {
-			g.introBarneyTalking = true
-			g.barneyIntroTextSound.PlayOnce()
-		}
-		if g.currentIntroPCImage < 2 && g.introCountUp%10 == 0 {
-			g.currentIntroPCImage = 1 - g.currentIntroPCImage
-		}
-		if g.introCountUp == 580 {
-			g.currentIntroPCImage = 2
-		}
-		if g.introCountUp == 620 {
-			g.losingSound.PlayOnce()
-		}
-		if g.introCountUp == 700 {
-			g.introInstructions.PlayOnce()
-		}
+func (g *Game) quit() {
+	g.running = false
+	if recordingInput {
+		saveRecordedInputs()
+	}
+}
 
-		if g.introCountUp >= IntroDuration {
-			g.prePlayCountDown = PrePlayFrameDelay
-			g.state = PrePlaying
-		}
-	} else if g.state == Playing {
-		for len(g.aiInputs) > 0 && g.aiInputs[0].frame == frame {
-			if g.aiInputs[0].event.Action != QuitGame {
-				g.aiInputs[0].event.CharacterIndex = 1
-				g.HandleInput(g.aiInputs[0].event)
-			}
-			g.aiInputs = g.aiInputs[1:]
-		}
+// endIntro starts the count down to the race when the intro is over or was
+// skipped.
+func (g *Game) endIntro() {
+	g.prePlayCountDown = PrePlayFrameDelay
+	g.state = PrePlaying
+	g.startCameraPath(g.level.Flyover)
+}
+
+// skipFlyover lets the player start the race without watching the whole
+// flyover.
+func (g *Game) skipFlyover() {
+	g.cameraPath.stop()
+	g.focusCameraOnPlayers()
+}
 
-		frame++
+func (g *Game) Update() {
+	g.updateMusic()
+
+	if g.state == PlayingCutScene {
+		g.updateCutScene()
+	} else if g.state == Playing {
+		g.frame++
 
-		g.updateCharacter(0)
-		g.updateCharacter(1)
+		g.updateRacers()
+		g.updateGhost()
+		g.respawnFallenRacers()
 
-		if !g.dieBounds.Overlaps(g.characters[0].Position) {
+		player := g.racers[g.primaryCharIndex]
+		if !g.versus && !g.dieBounds.Overlaps(player.character.Position) {
 			g.fallingSound.PlayOnce()
+			g.cameraControl.shake(deathShake, shakeDuration)
 			g.state = PlayerDying
 			g.playerDyingCountDown = PlayerDyingDelay
 		}
 
-		if g.goalBounds.Contains(g.characters[0].Position) {
+		// the race is over as soon as the first racer reaches the goal
+		g.checkSplits()
+		g.checkFinishers()
+		if g.versus {
+			if len(g.finishOrder) > 0 {
+				g.winnerIndex = g.finishOrder[0]
+				g.winningSound.PlayOnce()
+				g.versusWinCountDown = VersusWinDelay
+				g.state = VersusWinning
+			}
+		} else if player.finished {
 			g.winningSound.PlayOnce()
 			g.playerWinCountDown = PlayerWinDelay
 			g.state = PlayerWinning
-		} else if g.goalBounds.Contains(g.characters[1].Position) {
+		} else if len(g.finishOrder) > 0 {
+			g.winnerIndex = g.finishOrder[0]
 			g.losingSound.PlayOnce()
 			g.state = PlayerRealizingLoss
 			g.losingSoundCountDown = LosingSoundDelay
 		}
 
-		g.camera.CenterAround(g.characters[g.primaryCharIndex].Position.Center())
+		g.focusCameraOnPlayers()
 	} else if g.state == PrePlaying {
-		g.camera.CenterAround(g.characters[g.primaryCharIndex].Position.Center())
+		if g.cameraPath.playing() {
+			// the count down starts after the flyover
+			g.updateCameraPath()
+			return
+		}
+		g.focusCameraOnPlayers()
 		g.prePlayCountDown--
 		if g.prePlayCountDown == WhistleSoundDuration {
 			g.whistleSound.PlayOnce()
@@ -230,53 +382,112 @@ func (g *Game) Update() {
 			g.state = Playing
 		}
 	} else if g.state == PlayerDying {
+		g.focusCameraOnPlayers()
 		g.playerDyingCountDown--
 		if g.playerDyingCountDown <= 0 {
 			g.resetLevel()
 		}
 	} else if g.state == PlayerWinning {
-		g.characters[0].Reset(LeftDirectionIndex)
-		g.characters[1].Reset(RightDirectionIndex)
+		for i, r := range g.racers {
+			if i == g.primaryCharIndex {
+				r.character.Reset(LeftDirectionIndex)
+			} else {
+				r.character.Reset(RightDirectionIndex)
+			}
+		}
 		g.playerWinCountDown--
 		if g.playerWinCountDown < 0 {
-			// TODO go to end cut-scene
+			g.playCutScene(&endScene, func() { g.showMainMenu(0) })
 		}
 	} else if g.state == PlayerRealizingLoss {
 		g.losingSoundCountDown--
 		if g.losingSoundCountDown <= 0 {
 			g.state = CameraShowsBarneyWinning
-			g.barneyWinCountDown = BarneyWinDelay
-			g.barneyWinSound.PlayOnce()
-			g.characters[1].Reset(LeftDirectionIndex)
+			g.startCameraPath(winnerShot)
+			// the camera only moves to the winner now, until then his
+			// cheering comes from off-screen
+			x, y := g.racers[g.winnerIndex].character.Position.Center()
+			g.playSoundAt(g.barneyWinSound, x, y)
+			g.racers[g.winnerIndex].character.Reset(LeftDirectionIndex)
 		}
 	} else if g.state == CameraShowsBarneyWinning {
-		g.camera.CenterAround(g.characters[1].Position.Center())
-		g.barneyWinCountDown--
-		if g.barneyWinCountDown <= 0 {
+		g.updateCameraPath()
+		if !g.cameraPath.playing() {
+			g.resetLevel()
+		}
+	} else if g.state == VersusWinning {
+		winner := g.racers[g.winnerIndex].character
+		winner.Reset(LeftDirectionIndex)
+		g.camera.CenterAround(winner.Position.Center())
+		g.versusWinCountDown--
+		if g.versusWinCountDown <= 0 {
 			g.resetLevel()
 		}
 	}
 }
 
-func (g *Game) resetLevel() {
-	g.characters[0].SetBottomCenterTo(500, 537)
-	g.characters[0].Reset(RightDirectionIndex)
+// focusCameraOnPlayers follows the player or, in versus mode, all players
+// with a split screen if the camera supports it.
+func (g *Game) focusCameraOnPlayers() {
+	if multiView, ok := g.camera.(MultiViewCamera); ok && g.versus {
+		var points []Point
+		for _, i := range g.localPlayers() {
+			x, y := g.racers[i].character.Position.Center()
+			points = append(points, Point{x, y})
+		}
+		multiView.CenterViewsAround(points)
+		return
+	}
+	g.camera.CenterAround(g.cameraControl.follow(g.racers[g.primaryCharIndex].character))
+}
+
+// localPlayers returns the racers that are controlled by players at this
+// computer.
+func (g *Game) localPlayers() []int {
+	var players []int
+	for i, r := range g.racers {
+		if r.controller == nil && !r.remote {
+			players = append(players, i)
+		}
+	}
+	return players
+}
 
-	g.characters[1].SetBottomCenterTo(300, 537)
-	g.characters[1].Reset(RightDirectionIndex)
+// viewPlayer returns the racer whose HUD is drawn into the view. In versus
+// mode every view has the HUD of the player that it follows.
+func (g *Game) viewPlayer(multiView MultiViewCamera, view int) int {
+	players := g.localPlayers()
+	if i := multiView.ViewPoint(view); g.versus && i < len(players) {
+		return players[i]
+	}
+	return g.primaryCharIndex
+}
 
-	g.aiInputs = make([]inputRecord, len(recordedInputs))
-	copy(g.aiInputs, recordedInputs)
-	frame = 0
+func (g *Game) resetLevel() {
+	g.resetRacers()
+	g.cameraControl.reset(g.racers[g.primaryCharIndex].character.Position.Center())
+	g.cameraPath.stop()
+	g.resetGhost()
+	g.restartRunRecording()
+	g.splitResults = nil
+	g.frame = 0
 
 	g.state = PrePlaying
 	g.prePlayCountDown = PrePlayFrameDelay
 }
 
 func (g *Game) updateCharacter(charIndex int) {
-	char := g.characters[charIndex]
-	inputState := &g.inputStates[charIndex]
+	r := g.racers[charIndex]
+	wasInAir, speedY := r.character.InAir, r.character.SpeedY
+	moveCharacter(r.character, &r.input, g)
+	if charIndex == g.primaryCharIndex && wasInAir && !r.character.InAir &&
+		speedY >= hardLandingSpeedY {
+		g.cameraControl.shake(hardLandingShake, shakeDuration)
+	}
+}
 
+// moveCharacter applies the physics for the given input to the character.
+func moveCharacter(char *Character, inputState *inputState, collider Collider) {
 	// decelerate to 0
 	if char.SpeedX > 0 {
 		char.SpeedX -= char.Params.DecelerationX
@@ -325,7 +536,7 @@ func (g *Game) updateCharacter(charIndex int) {
 		char.SpeedY = char.Params.MaxSpeedY
 	}
 
-	char.Update(g)
+	char.Update(collider)
 }
 
 func (g *Game) MoveInX(bounds Rectangle, dx int) (newBounds Rectangle, collided bool) {
@@ -406,28 +617,61 @@ func (g *Game) Running() bool {
 	return g.running
 }
 
+// Paused returns true while the pause menu is shown, nothing moves and no
+// count down runs until the game is resumed.
+func (g *Game) Paused() bool {
+	return g.state == Paused
+}
+
+// Frame returns the number of frames since the start of the race.
+func (g *Game) Frame() int {
+	return g.frame
+}
+
 func (g *Game) Render() {
-	if g.state == IntroPCScene {
-		x, y := 1000, 0
-		g.camera.CenterAround(x, y)
-		g.graphics.ClearScreen(0, 0, 0)
-
-		img := g.introPC1
-		if g.introBarneyTalking && g.currentIntroPCImage == 1 {
-			img = g.introPC2
-		}
-		if g.currentIntroPCImage == 2 {
-			img = g.introGophette
-		}
+	g.updateMinimap()
+	g.render(g.state)
+}
 
-		w, h := img.Size()
-		img.DrawAt(x-w/2, y-h/2)
-	} else {
-		for i := range g.imageObjects {
-			g.imageObjects[i].Render()
+// render draws the game like it looks in the given state, the pause menu
+// draws the paused state underneath itself.
+func (g *Game) render(state GameState) {
+	if state == Paused {
+		g.renderPauseMenu()
+	} else if isMenu(state) {
+		g.renderMenu(state)
+	} else if state == PlayingCutScene {
+		g.renderCutScene()
+	} else if multiView, ok := g.camera.(MultiViewCamera); ok {
+		// draw the world and the HUD of its player once for every view, there
+		// is only one minimap, in the first view
+		for i := 0; i < multiView.ViewCount(); i++ {
+			viewport := multiView.SelectView(i)
+			g.graphics.SetClipRect(viewport)
+			g.renderWorld()
+			g.renderHUD(viewport, g.viewPlayer(multiView, i))
+			if i == 0 {
+				g.renderMinimap(viewport)
+			}
 		}
+		g.graphics.SetClipRect(Rectangle{})
+	} else {
+		g.renderWorld()
+		w, h := g.graphics.ScreenSize()
+		g.renderHUD(Rectangle{0, 0, w, h}, g.primaryCharIndex)
+		g.renderMinimap(Rectangle{0, 0, w, h})
+	}
+}
+
+func (g *Game) renderWorld() {
+	for i := range g.imageObjects {
+		g.imageObjects[i].Render()
+	}
+
+	g.renderGhost()
 
-		g.characters[1].Render()
-		g.characters[0].Render()
+	// draw the first racer (usually the player) on top
+	for i := len(g.racers) - 1; i >= 0; i-- {
+		g.racers[i].character.Render()
 	}
 }
@@ -0,0 +1,166 @@
+package main
+
+// gamepadSource turns the state of one gamepad into InputEvents. The D-pad or
+// the left stick go left and right, A jumps. In the menus, A confirms and the
+// D-pad or the stick move the selection. B and Back go back, Start confirms in
+// the menus and pauses the race.
+//
+// The gamepad's events are passed in as axis and button changes so the
+// mapping does not depend on the window library. An InputEvent is only made
+// when an action's state changes, e.g. pressing left on the D-pad while the
+// stick is already held left does nothing.
+
+type GamepadAxis int
+
+const (
+	StickX GamepadAxis = iota
+	StickY
+)
+
+type GamepadButton int
+
+const (
+	ButtonA GamepadButton = iota
+	ButtonB
+	ButtonBack
+	ButtonStart
+	DPadLeft
+	DPadRight
+	DPadUp
+	DPadDown
+)
+
+const (
+	gamepadAxisMax = 32767
+	// gamepadDeadZone is how far the stick must be pushed before it counts,
+	// worn sticks do not go back to exactly 0
+	gamepadDeadZone = gamepadAxisMax / 4
+)
+
+type gamepadSource struct {
+	stickX, stickY int
+	buttons        map[GamepadButton]bool
+	// down are the actions that are currently held down
+	down map[InputAction]bool
+	// buttonActions remembers which action a button press made so letting go
+	// of it releases the same action, even if the menu was left in between
+	buttonActions map[GamepadButton]InputAction
+	changes       []gamepadChange
+}
+
+// gamepadChange is an axis movement or a button press or release, in the
+// order in which they happened.
+type gamepadChange struct {
+	isAxis  bool
+	axis    GamepadAxis
+	value   int
+	button  GamepadButton
+	pressed bool
+}
+
+func newGamepadSource() *gamepadSource {
+	return &gamepadSource{
+		buttons:       make(map[GamepadButton]bool),
+		down:          make(map[InputAction]bool),
+		buttonActions: make(map[GamepadButton]InputAction),
+	}
+}
+
+// axisMoved is called when the axis changes, value goes from -32768 to 32767.
+func (s *gamepadSource) axisMoved(axis GamepadAxis, value int) {
+	s.changes = append(s.changes, gamepadChange{isAxis: true, axis: axis, value: value})
+}
+
+func (s *gamepadSource) buttonChanged(button GamepadButton, pressed bool) {
+	s.changes = append(s.changes, gamepadChange{button: button, pressed: pressed})
+}
+
+// disconnected lets go of everything, the next call to NextInputs releases
+// all actions that are held down.
+func (s *gamepadSource) disconnected() {
+	s.axisMoved(StickX, 0)
+	s.axisMoved(StickY, 0)
+	for button, pressed := range s.buttons {
+		if pressed {
+			s.buttonChanged(button, false)
+		}
+	}
+}
+
+func (s *gamepadSource) NextInputs(g *Game, charIndex, frame int) []InputEvent {
+	var events []InputEvent
+	set := func(action InputAction, down bool) {
+		if s.down[action] != down {
+			s.down[action] = down
+			events = append(events, InputEvent{action, down, charIndex})
+		}
+	}
+
+	for _, c := range s.changes {
+		if c.isAxis {
+			if c.axis == StickX {
+				s.stickX = c.value
+			} else {
+				s.stickY = c.value
+			}
+		} else {
+			if s.buttons[c.button] == c.pressed {
+				continue
+			}
+			s.buttons[c.button] = c.pressed
+			if c.pressed {
+				if action, ok := s.buttonAction(g, c.button); ok {
+					s.buttonActions[c.button] = action
+					set(action, true)
+				}
+			} else if action, ok := s.buttonActions[c.button]; ok {
+				delete(s.buttonActions, c.button)
+				set(action, s.buttonHolds(action) || action == Jump && s.stickUp(g))
+			}
+		}
+		set(GoLeft, s.buttons[DPadLeft] || s.stickX < -gamepadDeadZone)
+		set(GoRight, s.buttons[DPadRight] || s.stickX > gamepadDeadZone)
+		set(GoDown, s.buttons[DPadDown] || s.stickY > gamepadDeadZone)
+		set(Jump, s.buttonHolds(Jump) || s.stickUp(g))
+	}
+	s.changes = s.changes[:0]
+	return events
+}
+
+// buttonHolds is true if any button that is still pressed made the action,
+// e.g. A and up on the D-pad both jump.
+func (s *gamepadSource) buttonHolds(action InputAction) bool {
+	for _, a := range s.buttonActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// stickUp is true if the stick moves the menu selection up, in the race only
+// buttons jump so running with the stick does not jump by accident.
+func (s *gamepadSource) stickUp(g *Game) bool {
+	return g.inMenu() && s.stickY < -gamepadDeadZone
+}
+
+// buttonAction returns the action for a button that is not a direction.
+func (s *gamepadSource) buttonAction(g *Game, button GamepadButton) (InputAction, bool) {
+	switch button {
+	case ButtonA:
+		if g.inMenu() {
+			return Confirm, true
+		}
+		return Jump, true
+	case DPadUp:
+		return Jump, true
+	case ButtonB, ButtonBack:
+		return Back, true
+	case ButtonStart:
+		if g.inMenu() {
+			return Confirm, true
+		}
+		return Back, true
+	}
+	return 0, false
+}
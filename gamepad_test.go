@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func padAxis(a GamepadAxis, value int) gamepadChange {
+	return gamepadChange{isAxis: true, axis: a, value: value}
+}
+
+func padPress(b GamepadButton) gamepadChange {
+	return gamepadChange{button: b, pressed: true}
+}
+
+func padRelease(b GamepadButton) gamepadChange {
+	return gamepadChange{button: b}
+}
+
+func TestGamepadMapping(t *testing.T) {
+	const (
+		inDeadZone = gamepadDeadZone
+		pushed     = gamepadDeadZone + 1
+	)
+	down := func(a InputAction) InputEvent { return InputEvent{a, true, 0} }
+	up := func(a InputAction) InputEvent { return InputEvent{a, false, 0} }
+
+	tests := []struct {
+		name   string
+		state  GameState
+		inputs []gamepadChange
+		events []InputEvent
+	}{
+		{"stick right", Playing,
+			[]gamepadChange{padAxis(StickX, pushed), padAxis(StickX, 0)},
+			[]InputEvent{down(GoRight), up(GoRight)}},
+		{"stick left", Playing,
+			[]gamepadChange{padAxis(StickX, -pushed)},
+			[]InputEvent{down(GoLeft)}},
+		{"dead zone", Playing,
+			[]gamepadChange{padAxis(StickX, inDeadZone),
+				padAxis(StickX, -inDeadZone), padAxis(StickY, inDeadZone),
+				padAxis(StickY, -inDeadZone)},
+			nil},
+		{"stick down", Playing,
+			[]gamepadChange{padAxis(StickY, pushed)},
+			[]InputEvent{down(GoDown)}},
+		{"stick up does not jump in the race", Playing,
+			[]gamepadChange{padAxis(StickY, -pushed), padAxis(StickY, 0)},
+			nil},
+		{"stick up in the menu", MainMenu,
+			[]gamepadChange{padAxis(StickY, -pushed), padAxis(StickY, 0)},
+			[]InputEvent{down(Jump), up(Jump)}},
+		{"stick down in the menu", MainMenu,
+			[]gamepadChange{padAxis(StickY, pushed)},
+			[]InputEvent{down(GoDown)}},
+		{"A jumps", Playing,
+			[]gamepadChange{padPress(ButtonA), padRelease(ButtonA)},
+			[]InputEvent{down(Jump), up(Jump)}},
+		{"A confirms in the menu", MainMenu,
+			[]gamepadChange{padPress(ButtonA), padRelease(ButtonA)},
+			[]InputEvent{down(Confirm), up(Confirm)}},
+		{"A and D-pad up both jump", Playing,
+			[]gamepadChange{padPress(ButtonA), padPress(DPadUp),
+				padRelease(ButtonA), padRelease(DPadUp)},
+			[]InputEvent{down(Jump), up(Jump)}},
+		{"releasing D-pad up with the stick up in the menu", MainMenu,
+			[]gamepadChange{padPress(DPadUp), padAxis(StickY, -pushed),
+				padRelease(DPadUp)},
+			[]InputEvent{down(Jump)}},
+		{"D-pad and stick overlap", Playing,
+			[]gamepadChange{padAxis(StickX, -pushed), padPress(DPadLeft),
+				padAxis(StickX, 0), padRelease(DPadLeft)},
+			[]InputEvent{down(GoLeft), up(GoLeft)}},
+		{"B goes back", Playing,
+			[]gamepadChange{padPress(ButtonB)},
+			[]InputEvent{down(Back)}},
+		{"Start pauses the race", Playing,
+			[]gamepadChange{padPress(ButtonStart)},
+			[]InputEvent{down(Back)}},
+		{"Start confirms in the menu", MainMenu,
+			[]gamepadChange{padPress(ButtonStart)},
+			[]InputEvent{down(Confirm)}},
+		{"pressing twice counts once", Playing,
+			[]gamepadChange{padPress(ButtonB), padPress(ButtonB)},
+			[]InputEvent{down(Back)}},
+	}
+
+	for _, test := range tests {
+		g := newHeadlessGame(&level1)
+		g.state = test.state
+		s := newGamepadSource()
+		for _, in := range test.inputs {
+			if in.isAxis {
+				s.axisMoved(in.axis, in.value)
+			} else {
+				s.buttonChanged(in.button, in.pressed)
+			}
+		}
+		events := s.NextInputs(g, 0, 0)
+		if !reflect.DeepEqual(events, test.events) {
+			t.Errorf("%s: want %v but have %v", test.name, test.events, events)
+		}
+	}
+}
+
+func TestGamepadDisconnectReleasesEverything(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.state = Playing
+	s := newGamepadSource()
+	s.axisMoved(StickX, gamepadAxisMax)
+	s.buttonChanged(ButtonA, true)
+	s.NextInputs(g, 0, 0)
+
+	s.disconnected()
+	events := s.NextInputs(g, 0, 0)
+	want := []InputEvent{{GoRight, false, 0}, {Jump, false, 0}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("want %v but have %v", want, events)
+	}
+}
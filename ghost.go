@@ -0,0 +1,105 @@
+package main
+
+// The ghost replays the player's fastest finished run on the current level
+// so the player can race against it. It is not one of the racers: it only
+// collides with the level (so the replay takes the same path), it cannot
+// finish and the camera does not follow it.
+
+const ghostAlpha = 100
+
+type bestRun struct {
+	frames int
+	// splits are the frames at which the splits were reached, see splits.go
+	splits []int
+	inputs []inputRecord
+}
+
+type ghost struct {
+	character  Character
+	controller *replayController
+	input      inputState
+	frames     int
+	frame      int
+}
+
+// completeRun is called when the player reaches the goal. The run replaces
+// the best run on this level if it is faster, the ghost shows it from the
+// next attempt on.
+func (g *Game) completeRun() {
+	if g.versus {
+		return
+	}
+	best, ok := g.bestRuns[g.level.Name]
+	if ok && best.frames <= g.frame {
+		return
+	}
+	inputs := make([]inputRecord, len(g.runRecorder.records))
+	copy(inputs, g.runRecorder.records)
+	splits := make([]int, len(g.racers[g.primaryCharIndex].splitFrames))
+	copy(splits, g.racers[g.primaryCharIndex].splitFrames)
+	g.bestRuns[g.level.Name] = bestRun{frames: g.frame, splits: splits, inputs: inputs}
+	g.saveTimes()
+}
+
+// restartRunRecording starts recording a new attempt. Keys that the player is
+// already holding are recorded as pressed at the start.
+func (g *Game) restartRunRecording() {
+	g.runRecorder.reset()
+	index := g.primaryCharIndex
+	held := g.racers[index].input
+	if held.leftDown {
+		g.runRecorder.record(0, InputEvent{GoLeft, true, index})
+	}
+	if held.rightDown {
+		g.runRecorder.record(0, InputEvent{GoRight, true, index})
+	}
+	// holding jump only matters after a jump was started
+	if held.mustJumpThisFrame {
+		g.runRecorder.record(0, InputEvent{Jump, true, index})
+	}
+}
+
+// resetGhost puts the ghost back to the player's start, replaying the current
+// best run.
+func (g *Game) resetGhost() {
+	best, ok := g.bestRuns[g.level.Name]
+	if !ok || g.versus {
+		g.ghost = nil
+		return
+	}
+
+	player := g.racers[g.primaryCharIndex]
+	if g.ghost == nil {
+		// the ghost looks like the player
+		g.ghost = &ghost{character: *player.character}
+	}
+	g.ghost.controller = newReplayController(best.inputs)
+	g.ghost.input = inputState{}
+	g.ghost.frames = best.frames
+	g.ghost.frame = 0
+	g.ghost.character.Params = player.defaultParams
+	g.ghost.character.SetBottomCenterTo(player.spawn.X, player.spawn.Y)
+	g.ghost.character.Reset(RightDirectionIndex)
+}
+
+func (g *Game) updateGhost() {
+	ghost := g.ghost
+	if ghost == nil || ghost.frame >= ghost.frames {
+		return
+	}
+	for _, event := range ghost.controller.NextInputs(g, -1, ghost.frame) {
+		ghost.input.apply(event)
+	}
+	ghost.frame++
+	moveCharacter(&ghost.character, &ghost.input, g)
+	if ghost.frame >= ghost.frames {
+		// the run is over, wait in the goal
+		ghost.character.Reset(ghost.character.Direction)
+	}
+}
+
+func (g *Game) renderGhost() {
+	if g.ghost != nil {
+		g.ghost.character.RenderAlpha(ghostAlpha)
+	}
+}
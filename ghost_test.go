@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestGhostReplaysTheBestRun(t *testing.T) {
+	path := t.TempDir() + "/times.json"
+	g := newHeadlessGame(&level1)
+	if err := g.LoadTimes(path); err != nil {
+		t.Fatal(err)
+	}
+	raceHero(t, g)
+	finish := g.racers[0].character.Position
+
+	// the run was saved, a new game loads it for the ghost
+	g = newHeadlessGame(&level1)
+	if err := g.LoadTimes(path); err != nil {
+		t.Fatal(err)
+	}
+	if g.ghost == nil {
+		t.Fatal("there is no ghost")
+	}
+	g.SetController(1, newReplayController(nil))
+	g.state = Playing
+	for g.ghost.frame < g.ghost.frames && g.frame < 5000 {
+		g.Update()
+	}
+
+	if g.ghost.character.Position != finish {
+		t.Errorf("the ghost stopped at %v, not at %v",
+			g.ghost.character.Position, finish)
+	}
+	if !g.goalBounds.Contains(g.ghost.character.Position) {
+		t.Error("the ghost is not in the goal")
+	}
+	if g.state != Playing || len(g.finishOrder) != 0 {
+		t.Errorf("the ghost finished the race, the state is %v", g.state)
+	}
+}
+
+func TestOnlyFasterRunsReplaceTheGhost(t *testing.T) {
+	path := t.TempDir() + "/times.json"
+	g := newHeadlessGame(&level1)
+	if err := g.LoadTimes(path); err != nil {
+		t.Fatal(err)
+	}
+	finishRun := func(frames int, action InputAction) {
+		g.restartRunRecording()
+		g.runRecorder.record(1, InputEvent{action, true, 0})
+		g.frame = frames
+		g.completeRun()
+	}
+
+	for _, c := range []struct {
+		frames int
+		action InputAction
+		// want is the best run's frames and first action after the run
+		want       int
+		wantAction InputAction
+	}{
+		{500, GoRight, 500, GoRight},
+		{600, GoLeft, 500, GoRight},
+		{500, GoLeft, 500, GoRight},
+		{400, Jump, 400, Jump},
+	} {
+		finishRun(c.frames, c.action)
+		best := g.bestRuns[level1.Name]
+		if best.frames != c.want || best.inputs[0].event.Action != c.wantAction {
+			t.Errorf("after a run of %d frames: want the best run %d, %v "+
+				"but have %d, %v", c.frames, c.want, c.wantAction,
+				best.frames, best.inputs[0].event.Action)
+		}
+	}
+
+	saved := newHeadlessGame(&level1)
+	if err := saved.LoadTimes(path); err != nil {
+		t.Fatal(err)
+	}
+	if best := saved.bestRuns[level1.Name]; best.frames != 400 {
+		t.Errorf("want the saved best run to take 400 frames but it takes %d",
+			best.frames)
+	}
+	if saved.ghost == nil || saved.ghost.frames != 400 {
+		t.Error("the ghost does not replay the saved best run")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+// The headless backend implements the AssetLoader and Graphics interfaces
+// without a window or audio device. It is used to simulate the game without
+// displaying it, e.g. for searching routes for Barney. Instead of playing
+// sounds, it can record them for tests. Recording is off by default because
+// route searches and leaderboard checks simulate many thousands of frames.
+
+type headlessAssetLoader struct {
+	// recording turns on recording the events below
+	recording bool
+	// soundEvents are all sounds that were played, musicEvents what was done
+	// with the music, both in order
+	soundEvents []soundEvent
+	musicEvents []musicEvent
+	// loadedImages are the ids of all images that were loaded, in order
+	loadedImages []string
+}
+
+// soundEvent is a sound that the headless backend would have played.
+type soundEvent struct {
+	id        string
+	group     SoundGroup
+	placement SoundPlacement
+	loop      bool
+}
+
+func (l *headlessAssetLoader) LoadImage(id string) Image {
+	if l.recording {
+		l.loadedImages = append(l.loadedImages, id)
+	}
+	return nullImage{}
+}
+
+func (l *headlessAssetLoader) LoadSound(id string, group SoundGroup) Sound {
+	return &headlessSound{l, id, group}
+}
+
+func (l *headlessAssetLoader) LoadMusic(id string) Music {
+	return &headlessMusic{l, id}
+}
+
+func (*headlessAssetLoader) SetVolumes(v Volumes) {}
+
+type nullImage struct{}
+
+func (nullImage) DrawAt(x, y int)                   {}
+func (nullImage) DrawAlphaAt(x, y int, alpha uint8) {}
+func (nullImage) Destroy()                          {}
+func (nullImage) Size() (width, height int)         { return 0, 0 }
+
+type headlessSound struct {
+	loader *headlessAssetLoader
+	id     string
+	group  SoundGroup
+}
+
+func (s *headlessSound) PlayOnce() {
+	s.Play(false)
+}
+
+func (s *headlessSound) Play(loop bool) PlayingSound {
+	return s.record(centered, loop)
+}
+
+func (s *headlessSound) PlayAt(p SoundPlacement) PlayingSound {
+	return s.record(p, false)
+}
+
+func (s *headlessSound) record(p SoundPlacement, loop bool) PlayingSound {
+	if s.loader.recording {
+		s.loader.soundEvents = append(
+			s.loader.soundEvents,
+			soundEvent{s.id, s.group, p, loop},
+		)
+	}
+	return nullPlayingSound{}
+}
+
+type nullPlayingSound struct{}
+
+func (nullPlayingSound) Stop()                    {}
+func (nullPlayingSound) FadeOut(milliseconds int) {}
+func (nullPlayingSound) Playing() bool            { return false }
+
+type musicEvent struct {
+	id     string
+	action string
+}
+
+type headlessMusic struct {
+	loader *headlessAssetLoader
+	id     string
+}
+
+func (m *headlessMusic) Play(fadeInMilliseconds int) { m.record("play") }
+func (m *headlessMusic) PlayOnce()                   { m.record("play once") }
+func (m *headlessMusic) Stop()                       { m.record("stop") }
+func (m *headlessMusic) FadeOut(milliseconds int)    { m.record("fade out") }
+
+func (m *headlessMusic) record(action string) {
+	if m.loader.recording {
+		m.loader.musicEvents = append(m.loader.musicEvents, musicEvent{m.id, action})
+	}
+}
+
+type headlessGraphics struct{}
+
+func (headlessGraphics) FillRect(rect Rectangle, r, g, b, a uint8)       {}
+func (headlessGraphics) FillScreenRect(rect Rectangle, r, g, b, a uint8) {}
+func (headlessGraphics) ClearScreen(r, g, b uint8)                       {}
+func (headlessGraphics) SetClipRect(rect Rectangle)                      {}
+
+func (headlessGraphics) DrawText(text string, x, y int, style TextStyle) {}
+
+func (headlessGraphics) TextSize(text string) (width, height int) {
+	return defaultFont.measure(text)
+}
+
+func (headlessGraphics) ScreenSize() (width, height int) {
+	return VirtualScreenW, VirtualScreenH
+}
+
+func (headlessGraphics) RenderToImage(width, height int, draw func()) Image {
+	draw()
+	return nullImage{}
+}
+
+// newHeadlessGame creates a game for the given level which does not display
+// anything or play sounds
+func newHeadlessGame(level *Level) *Game {
+	return NewGame(
+		&headlessAssetLoader{},
+		headlessGraphics{},
+		newWindowCamera(VirtualScreenW, VirtualScreenH),
+		level,
+		0,
+	)
+}
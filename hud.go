@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// The HUD is drawn on top of the race in screen coordinates. It shows a
+// player's race time, his last split, a progress bar from the start to the
+// goal with a marker for every racer and, if the rival is not on screen, an
+// arrow at the edge of the screen pointing at him. In split-screen, every
+// view gets the HUD of its player. The minimap (see minimap.go) is drawn
+// once, next to the HUD.
+
+const (
+	hudMargin         = 16
+	hudBarHeight      = 8
+	hudMarkerSize     = 16
+	hudArrowLength    = 36
+	hudArrowWidth     = 30
+	hudPixelsPerMeter = 100
+	// hudSplitFrames is how long the last split is shown
+	hudSplitFrames = 3 * FramesPerSecond
+)
+
+var (
+	heroColor   = Color{255, 130, 200, 255}
+	barneyColor = Color{90, 160, 255, 255}
+)
+
+// renderHUD draws the player's HUD into the given area of the screen, the
+// camera must show this area.
+func (g *Game) renderHUD(viewport Rectangle, player int) {
+	g.renderRaceTime(viewport, player)
+	g.renderProgressBar(viewport, player)
+	if rival := g.rivalIndex(player); rival != -1 {
+		g.renderRivalArrow(viewport, player, rival)
+	}
+}
+
+func (g *Game) renderRaceTime(viewport Rectangle, player int) {
+	style := DefaultTextStyle
+	style.Align = AlignRight
+	x, y := viewport.X+viewport.W-hudMargin, viewport.Y+hudMargin
+	g.graphics.DrawText(formatRaceTime(g.raceTime(player)), x, y, style)
+
+	if last, ok := g.lastSplitResult(player); ok {
+		if g.frame-last.Frames < hudSplitFrames || g.racers[player].finished {
+			_, lineH := g.graphics.TextSize("X")
+			g.graphics.DrawText(last.String(), x, y+lineH, style)
+		}
+	}
+}
+
+func (g *Game) renderProgressBar(viewport Rectangle, player int) {
+	bar := Rectangle{
+		viewport.X + viewport.W/4,
+		viewport.Y + viewport.H - hudMargin - hudMarkerSize/2 - hudBarHeight/2,
+		viewport.W / 2,
+		hudBarHeight,
+	}
+	g.graphics.FillScreenRect(bar.AddMargin(2), 0, 0, 0, 160)
+	g.graphics.FillScreenRect(bar, 255, 255, 255, 200)
+
+	// the viewport's player is drawn after the others so his marker is not
+	// hidden when racers are at the same place
+	for _, i := range g.progressBarOrder(player) {
+		x := bar.X + g.raceProgress(g.racers[i].character)*bar.W/1000
+		marker := Rectangle{
+			x - hudMarkerSize/2,
+			bar.Y + hudBarHeight/2 - hudMarkerSize/2,
+			hudMarkerSize,
+			hudMarkerSize,
+		}
+		c := g.racerColor(i)
+		g.graphics.FillScreenRect(marker.AddMargin(2), 0, 0, 0, 255)
+		g.graphics.FillScreenRect(marker, c.R, c.G, c.B, c.A)
+	}
+}
+
+// progressBarOrder returns all racers with the player last.
+func (g *Game) progressBarOrder(player int) []int {
+	order := make([]int, 0, len(g.racers))
+	for i := range g.racers {
+		if i != player {
+			order = append(order, i)
+		}
+	}
+	return append(order, player)
+}
+
+// raceProgress is the horizontal way from the hero's start to the goal that
+// the character has made, from 0 to 1000.
+func (g *Game) raceProgress(char *Character) int {
+	start := g.level.HeroStart.X
+	goal, _ := g.goalBounds.Center()
+	x, _ := char.Position.Center()
+	if goal == start {
+		return 1000
+	}
+	return clamp((x-start)*1000/(goal-start), 0, 1000)
+}
+
+func (g *Game) racerColor(i int) Color {
+	if i == 0 {
+		return heroColor
+	}
+	return barneyColor
+}
+
+// rivalIndex returns the racer, other than the player, who is closest to the
+// goal or -1 if there is none.
+func (g *Game) rivalIndex(player int) int {
+	rival := -1
+	for i, r := range g.racers {
+		if i == player {
+			continue
+		}
+		if rival == -1 ||
+			g.raceProgress(r.character) > g.raceProgress(g.racers[rival].character) {
+			rival = i
+		}
+	}
+	return rival
+}
+
+// renderRivalArrow draws an arrow at the viewport's edge that points at the
+// rival, if he is not visible, and shows how far away he is.
+func (g *Game) renderRivalArrow(viewport Rectangle, player, rivalIndex int) {
+	rival := g.racers[rivalIndex]
+	worldX, worldY := rival.character.Position.Center()
+	x, y := g.camera.WorldToScreen(worldX, worldY)
+	char := rival.character.Position
+	left, top := g.camera.WorldToScreen(char.X, char.Y)
+	right, bottom := g.camera.WorldToScreen(char.X+char.W, char.Y+char.H)
+	if viewport.Overlaps(Rectangle{left, top, right - left, bottom - top}) {
+		return
+	}
+
+	// go from the center of the viewport towards the rival until getting to
+	// the edge
+	cx, cy := viewport.Center()
+	dx, dy := float64(x-cx), float64(y-cy)
+	length := math.Hypot(dx, dy)
+	dx, dy = dx/length, dy/length
+	halfW := float64(viewport.W/2 - hudMargin)
+	halfH := float64(viewport.H/2 - hudMargin)
+	t := math.Min(halfW/math.Abs(dx), halfH/math.Abs(dy))
+	tipX, tipY := float64(cx)+dx*t, float64(cy)+dy*t
+	baseX, baseY := tipX-dx*hudArrowLength, tipY-dy*hudArrowLength
+	sideX, sideY := -dy*hudArrowWidth/2, dx*hudArrowWidth/2
+
+	c := g.racerColor(rivalIndex)
+	fillScreenTriangle(
+		g.graphics,
+		[3][2]float64{
+			{tipX, tipY},
+			{baseX + sideX, baseY + sideY},
+			{baseX - sideX, baseY - sideY},
+		},
+		c,
+	)
+
+	playerX, playerY := g.racers[player].character.Position.Center()
+	meters := int(math.Hypot(float64(worldX-playerX), float64(worldY-playerY))) /
+		hudPixelsPerMeter
+	_, textH := g.graphics.TextSize("X")
+	textX := int(baseX - dx*hudArrowLength/2)
+	textY := int(baseY-dy*hudArrowLength/2) - textH/2
+	style := DefaultTextStyle
+	style.Align = AlignCenter
+	g.graphics.DrawText(fmt.Sprintf("%dm", meters), textX, textY, style)
+}
+
+// fillScreenTriangle fills the triangle with one rectangle per line of
+// pixels.
+func fillScreenTriangle(graphics Graphics, corners [3][2]float64, c Color) {
+	minY := math.Min(corners[0][1], math.Min(corners[1][1], corners[2][1]))
+	maxY := math.Max(corners[0][1], math.Max(corners[1][1], corners[2][1]))
+	for y := math.Floor(minY); y <= maxY; y++ {
+		middle := y + 0.5
+		left, right := math.Inf(1), math.Inf(-1)
+		for i := range corners {
+			a, b := corners[i], corners[(i+1)%3]
+			if (a[1] <= middle) == (b[1] <= middle) {
+				continue // the edge does not cross this line
+			}
+			x := a[0] + (middle-a[1])*(b[0]-a[0])/(b[1]-a[1])
+			left = math.Min(left, x)
+			right = math.Max(right, x)
+		}
+		if left < right {
+			graphics.FillScreenRect(
+				Rectangle{int(left), int(y), int(right-left) + 1, 1},
+				c.R, c.G, c.B, c.A,
+			)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+// textGraphics records the texts that are drawn.
+type textGraphics struct {
+	headlessGraphics
+	texts []string
+}
+
+func (g *textGraphics) DrawText(text string, x, y int, style TextStyle) {
+	g.texts = append(g.texts, text)
+}
+
+func TestSplitScreenHUDForEveryPlayer(t *testing.T) {
+	graphics := &textGraphics{}
+	g := NewGame(
+		&headlessAssetLoader{},
+		graphics,
+		newWindowCamera(VirtualScreenW, VirtualScreenH),
+		&level1,
+		0,
+	)
+	g.SetController(1, nil)
+	g.SetVersus(true)
+	g.state = Playing
+	// the second player is far behind and higher up, so he is in the left
+	// or upper view, whichever way the screen is split
+	g.racers[0].character.Position.X, g.racers[0].character.Position.Y = 5000, 400
+	g.racers[1].character.Position.X, g.racers[1].character.Position.Y = 500, 0
+	g.frame = 300
+	g.racers[0].finished = true
+	g.racers[0].finishFrame = 100
+	g.racers[1].splitFrames = []int{250}
+	g.focusCameraOnPlayers()
+
+	camera := g.camera.(MultiViewCamera)
+	if camera.ViewCount() != 2 {
+		t.Fatal("the screen is not split")
+	}
+	for view, want := range []int{1, 0} {
+		if have := g.viewPlayer(camera, view); have != want {
+			t.Errorf("view %d: want player %d but have %d", view, want, have)
+		}
+	}
+
+	g.Render()
+	for _, want := range []string{
+		formatRaceTime(100),
+		formatRaceTime(300),
+		level1.Splits[0].Name + " " + formatRaceTime(250),
+	} {
+		found := false
+		for _, text := range graphics.texts {
+			found = found || text == want
+		}
+		if !found {
+			t.Errorf("%q was not drawn, the texts are %q", want, graphics.texts)
+		}
+	}
+}
@@ -13,6 +13,12 @@ const (
 	GoRight
 	Jump
 	QuitGame
+	// GoDown, Confirm and Back are used in the menus, Back also leaves the race
+	GoDown
+	Confirm
+	Back
+	ToggleMinimap
+	ToggleFullscreen
 )
 
 func (a InputAction) String() string {
@@ -25,6 +31,16 @@ func (a InputAction) String() string {
 		return "Jump"
 	case QuitGame:
 		return "QuitGame"
+	case GoDown:
+		return "GoDown"
+	case Confirm:
+		return "Confirm"
+	case Back:
+		return "Back"
+	case ToggleMinimap:
+		return "ToggleMinimap"
+	case ToggleFullscreen:
+		return "ToggleFullscreen"
 	default:
 		return "unknown input"
 	}
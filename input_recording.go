@@ -17,40 +17,59 @@ type inputRecord struct {
 	event InputEvent
 }
 
-var (
-	inputs []inputRecord
-	frame  int
-)
+// An inputRecorder keeps the inputs for one character.
+type inputRecorder struct {
+	characterIndex int
+	records        []inputRecord
+}
+
+func (r *inputRecorder) record(frame int, event InputEvent) {
+	if event.CharacterIndex == r.characterIndex {
+		r.records = append(r.records, inputRecord{frame: frame, event: event})
+	}
+}
+
+func (r *inputRecorder) reset() {
+	r.records = nil
+}
+
+var inputs = inputRecorder{characterIndex: recordedCharacterIndex}
 
 func init() {
 	if replayingInput {
-		inputs = recordedInputs
+		inputs.records = recordedInputs
 	}
 }
 
-func recordInput(event InputEvent) {
-	if recordingInput && event.CharacterIndex == recordedCharacterIndex {
-		inputs = append(inputs, inputRecord{frame: frame, event: event})
+func recordInput(frame int, event InputEvent) {
+	if recordingInput {
+		inputs.record(frame, event)
 	}
 }
 
 func saveRecordedInputs() {
+	writeInputRecords("./recorded_inputs.go", "recordedInputs", inputs.records)
+}
+
+// writeInputRecords generates a Go file which defines the given records as a
+// variable with the given name
+func writeInputRecords(path, varName string, records []inputRecord) error {
 	input := bytes.NewBuffer(nil)
 	input.WriteString(`package main
 
-var recordedInputs = []inputRecord{
+var ` + varName + ` = []inputRecord{
 `)
-	for i := range inputs {
+	for i := range records {
 		fmt.Fprintf(
 			input,
 			"\t{%v, InputEvent{%v, %v, %v}},\n",
-			inputs[i].frame,
-			inputs[i].event.Action,
-			inputs[i].event.Pressed,
-			inputs[i].event.CharacterIndex,
+			records[i].frame,
+			records[i].event.Action,
+			records[i].event.Pressed,
+			records[i].event.CharacterIndex,
 		)
 	}
 	input.WriteString(`}
 `)
-	ioutil.WriteFile("./recorded_inputs.go", input.Bytes(), 0777)
+	return ioutil.WriteFile(path, input.Bytes(), 0777)
 }
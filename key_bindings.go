@@ -0,0 +1,200 @@
+package main
+
+// KeyBindings map keys to InputActions. Keys are identified by their names,
+// e.g. "Left", "Space" or "A", so the game does not depend on the window
+// library's key codes. An action can have several keys.
+
+const maxKeysPerAction = 3
+
+type KeyBindings struct {
+	keys map[InputAction][]string
+}
+
+// boundActions are the actions that can be bound to keys, in the order in
+// which the controls menu lists them.
+var boundActions = []InputAction{
+	GoLeft,
+	GoRight,
+	Jump,
+	GoDown,
+	Confirm,
+	Back,
+	ToggleMinimap,
+	ToggleFullscreen,
+}
+
+func defaultKeyBindings() *KeyBindings {
+	return &KeyBindings{keys: map[InputAction][]string{
+		GoLeft:           {"Left"},
+		GoRight:          {"Right"},
+		Jump:             {"Up"},
+		GoDown:           {"Down"},
+		Confirm:          {"Return"},
+		Back:             {"Escape"},
+		ToggleMinimap:    {"M"},
+		ToggleFullscreen: {"F11"},
+	}}
+}
+
+// defaultSecondPlayerBindings are for Barney in local versus mode.
+func defaultSecondPlayerBindings() *KeyBindings {
+	return &KeyBindings{keys: map[InputAction][]string{
+		GoLeft:  {"A"},
+		GoRight: {"D"},
+		Jump:    {"W"},
+	}}
+}
+
+// Actions returns all actions that are bound to the key.
+func (b *KeyBindings) Actions(key string) []InputAction {
+	var actions []InputAction
+	for _, action := range boundActions {
+		for _, k := range b.keys[action] {
+			if k == key {
+				actions = append(actions, action)
+			}
+		}
+	}
+	return actions
+}
+
+// Keys returns the keys bound to the action.
+func (b *KeyBindings) Keys(action InputAction) []string {
+	return b.keys[action]
+}
+
+// Bind adds the key to the action. If the action already has
+// maxKeysPerAction keys, the oldest one is replaced. The key is taken away
+// from other actions. If it was another action's only key, the keys are
+// swapped: that action gets the oldest key of this action so no action is
+// left without a key. If this action has no key to swap, nothing changes.
+func (b *KeyBindings) Bind(action InputAction, key string) {
+	keys := removeKey(b.keys[action], key)
+	swapped := b.actionsWithOnlyKey(key)
+	if len(swapped) > len(keys) {
+		return
+	}
+
+	for _, other := range boundActions {
+		if other != action {
+			b.keys[other] = removeKey(b.keys[other], key)
+		}
+	}
+	for _, other := range swapped {
+		b.keys[other] = []string{keys[0]}
+		keys = keys[1:]
+	}
+	keys = append(keys, key)
+	if len(keys) > maxKeysPerAction {
+		keys = keys[1:]
+	}
+	b.keys[action] = keys
+}
+
+// BindTakingFrom binds the key like Bind and takes it away from the other
+// player, both players' keys work in local versus mode. If it was the only key
+// of one of his actions, the players swap: his action gets the oldest key of
+// this action, which this player gives up. So moving one player to the other
+// player's keys moves the other player to the old keys. If this action has no
+// keys left to give, nothing changes.
+func (b *KeyBindings) BindTakingFrom(other *KeyBindings, action InputAction, key string) {
+	keys := removeKey(b.keys[action], key)
+	given := other.actionsWithOnlyKey(key)
+	if len(b.actionsWithOnlyKey(key))+len(given) > len(keys) {
+		return
+	}
+
+	for _, a := range boundActions {
+		other.keys[a] = removeKey(other.keys[a], key)
+	}
+	for _, a := range given {
+		other.keys[a] = []string{keys[0]}
+		b.keys[action] = removeKey(b.keys[action], keys[0])
+		keys = keys[1:]
+	}
+	b.Bind(action, key)
+}
+
+// actionsWithOnlyKey returns the actions that have no other key than key.
+func (b *KeyBindings) actionsWithOnlyKey(key string) []InputAction {
+	var actions []InputAction
+	for _, action := range boundActions {
+		if len(b.keys[action]) == 1 && b.keys[action][0] == key {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+func removeKey(keys []string, key string) []string {
+	var left []string
+	for _, k := range keys {
+		if k != key {
+			left = append(left, k)
+		}
+	}
+	return left
+}
+
+// keyBindingsFile has the key names by action name for both players, it is
+// part of the settings (see settings.go).
+type keyBindingsFile struct {
+	Player1 map[string][]string
+	Player2 map[string][]string
+}
+
+// setFromFile takes the keys for all actions that are in the file, the other
+// actions keep their keys.
+func (b *KeyBindings) setFromFile(keys map[string][]string) {
+	for _, action := range boundActions {
+		if names, ok := keys[action.String()]; ok {
+			b.keys[action] = names
+		}
+	}
+}
+
+func (b *KeyBindings) toFile() map[string][]string {
+	keys := make(map[string][]string)
+	for action, names := range b.keys {
+		keys[action.String()] = names
+	}
+	return keys
+}
+
+// KeyBindings returns the keys of the player controlling the game.
+func (g *Game) KeyBindings() *KeyBindings {
+	return g.keyBindings
+}
+
+// SecondKeyBindings returns Barney's keys in local versus mode.
+func (g *Game) SecondKeyBindings() *KeyBindings {
+	return g.secondKeyBindings
+}
+
+// WaitingForKey is true if the controls menu waits for a key to bind, the
+// next key press must go to BindKey instead of being mapped to an action.
+func (g *Game) WaitingForKey() bool {
+	return g.waitingForKey
+}
+
+// cancelBindingKey stops waiting for a key in the controls menu instead of
+// being bound.
+const cancelBindingKey = "Escape"
+
+// BindKey binds the key to the action selected in the controls menu, the key
+// is taken away from Barney's keys.
+func (g *Game) BindKey(key string) {
+	if !g.waitingForKey {
+		return
+	}
+	g.waitingForKey = false
+	if key != cancelBindingKey {
+		g.keyBindings.BindTakingFrom(
+			g.secondKeyBindings,
+			boundActions[g.menu.selected],
+			key,
+		)
+		g.saveSettings()
+	}
+	g.showControls(g.menu.selected)
+}
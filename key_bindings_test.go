@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindSwapsAnotherActionsOnlyKey(t *testing.T) {
+	b := defaultKeyBindings()
+	b.Bind(Jump, "Left")
+	if keys := b.Keys(Jump); !reflect.DeepEqual(keys, []string{"Left"}) {
+		t.Errorf("want Jump on Left but have %v", keys)
+	}
+	if keys := b.Keys(GoLeft); !reflect.DeepEqual(keys, []string{"Up"}) {
+		t.Errorf("want GoLeft to get Up but have %v", keys)
+	}
+}
+
+func TestBindTakesKeyFromActionWithSeveralKeys(t *testing.T) {
+	b := defaultKeyBindings()
+	b.Bind(GoLeft, "A")
+	b.Bind(Jump, "A")
+	if keys := b.Keys(GoLeft); !reflect.DeepEqual(keys, []string{"Left"}) {
+		t.Errorf("want GoLeft to keep Left but have %v", keys)
+	}
+	if keys := b.Keys(Jump); !reflect.DeepEqual(keys, []string{"Up", "A"}) {
+		t.Errorf("want Jump on Up and A but have %v", keys)
+	}
+}
+
+func TestBindReplacesOldestKey(t *testing.T) {
+	b := defaultKeyBindings()
+	for _, key := range []string{"W", "Space", "K"} {
+		b.Bind(Jump, key)
+	}
+	if keys := b.Keys(Jump); !reflect.DeepEqual(keys, []string{"W", "Space", "K"}) {
+		t.Errorf("want the three newest keys but have %v", keys)
+	}
+}
+
+func TestBindWithoutKeyToSwapChangesNothing(t *testing.T) {
+	b := defaultKeyBindings()
+	b.keys[Jump] = nil
+	b.Bind(Jump, "Left")
+	if len(b.Keys(Jump)) != 0 || !reflect.DeepEqual(b.Keys(GoLeft), []string{"Left"}) {
+		t.Errorf("the bind changed Jump to %v and GoLeft to %v", b.Keys(Jump), b.Keys(GoLeft))
+	}
+}
+
+func TestResetControlsResetsBothPlayers(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.keyBindings.Bind(Jump, "Space")
+	g.secondKeyBindings.Bind(Jump, "S")
+	g.showControls(len(boundActions) + resetControlsItem)
+	g.HandleInput(InputEvent{Confirm, true, 0})
+	if !reflect.DeepEqual(g.keyBindings, defaultKeyBindings()) {
+		t.Error("player 1's keys were not reset")
+	}
+	if !reflect.DeepEqual(g.secondKeyBindings, defaultSecondPlayerBindings()) {
+		t.Error("player 2's keys were not reset")
+	}
+}
+
+func TestEscapeCancelsBindingAKey(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.showControls(0)
+	g.HandleInput(InputEvent{Confirm, true, 0})
+	if !g.WaitingForKey() {
+		t.Fatal("the controls menu does not wait for a key")
+	}
+	g.BindKey("Escape")
+	if g.WaitingForKey() {
+		t.Error("the controls menu still waits for a key")
+	}
+	if !reflect.DeepEqual(g.keyBindings, defaultKeyBindings()) {
+		t.Errorf("Escape was bound, the keys are %v", g.keyBindings.keys)
+	}
+	if g.state != ControlsMenu || g.menu.selected != 0 {
+		t.Error("the controls menu was left")
+	}
+}
+
+func TestBindingTheOtherPlayersKeysSwapsThem(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	for _, bind := range []struct {
+		action InputAction
+		key    string
+	}{{GoLeft, "A"}, {GoRight, "D"}, {Jump, "W"}} {
+		for i, action := range boundActions {
+			if action == bind.action {
+				g.showControls(i)
+			}
+		}
+		g.HandleInput(InputEvent{Confirm, true, 0})
+		g.BindKey(bind.key)
+	}
+
+	for _, c := range []struct {
+		player *KeyBindings
+		action InputAction
+		want   []string
+	}{
+		{g.keyBindings, GoLeft, []string{"A"}},
+		{g.keyBindings, GoRight, []string{"D"}},
+		{g.keyBindings, Jump, []string{"W"}},
+		{g.secondKeyBindings, GoLeft, []string{"Left"}},
+		{g.secondKeyBindings, GoRight, []string{"Right"}},
+		{g.secondKeyBindings, Jump, []string{"Up"}},
+	} {
+		if keys := c.player.Keys(c.action); !reflect.DeepEqual(keys, c.want) {
+			t.Errorf("%v: want %v but have %v", c.action, c.want, keys)
+		}
+	}
+	// W only makes player 1 jump
+	if actions := g.secondKeyBindings.Actions("W"); len(actions) != 0 {
+		t.Errorf("W is still player 2's %v", actions)
+	}
+}
+
+func TestBindTakesKeyFromTheOtherPlayer(t *testing.T) {
+	b, other := defaultKeyBindings(), defaultSecondPlayerBindings()
+	other.Bind(Jump, "Space")
+	b.BindTakingFrom(other, Jump, "W")
+	if keys := b.Keys(Jump); !reflect.DeepEqual(keys, []string{"Up", "W"}) {
+		t.Errorf("want Jump on Up and W but have %v", keys)
+	}
+	if keys := other.Keys(Jump); !reflect.DeepEqual(keys, []string{"Space"}) {
+		t.Errorf("want player 2 to keep Space but have %v", keys)
+	}
+
+	// the other player's only key is not taken without one to give him
+	b, other = defaultKeyBindings(), defaultSecondPlayerBindings()
+	b.keys[Jump] = nil
+	b.BindTakingFrom(other, Jump, "W")
+	if len(b.Keys(Jump)) != 0 || !reflect.DeepEqual(other.Keys(Jump), []string{"W"}) {
+		t.Errorf("the bind changed Jump to %v and player 2's to %v",
+			b.Keys(Jump), other.Keys(Jump))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+// keyboardSource turns key presses into InputEvents using the player's key
+// bindings or, for the second player in local versus mode, Barney's.
+type keyboardSource struct {
+	secondPlayer bool
+	keys         []keyChange
+}
+
+type keyChange struct {
+	key     string
+	pressed bool
+}
+
+// keyChanged is called when a key, identified by its name, is pressed or let
+// go. The events are made in the next call to NextInputs.
+func (s *keyboardSource) keyChanged(key string, pressed bool) {
+	s.keys = append(s.keys, keyChange{key, pressed})
+}
+
+func (s *keyboardSource) NextInputs(g *Game, charIndex, frame int) []InputEvent {
+	bindings := g.KeyBindings()
+	if s.secondPlayer {
+		bindings = g.SecondKeyBindings()
+	}
+	var events []InputEvent
+	for _, k := range s.keys {
+		for _, action := range bindings.Actions(k.key) {
+			events = append(events, InputEvent{action, k.pressed, charIndex})
+		}
+	}
+	s.keys = s.keys[:0]
+	return events
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// The leaderboard keeps the fastest runs for every level. Every entry comes
+// with the inputs that produced it and when the leaderboard is loaded, each
+// run is simulated again. Entries whose run does not reach the goal in the
+// claimed frame are rejected so editing the file does not get anybody to the
+// top.
+
+const leaderboardSize = 10
+
+type LeaderboardEntry struct {
+	Name   string
+	Frames int
+	Inputs []savedInput
+}
+
+type leaderboardFile struct {
+	Levels map[string][]LeaderboardEntry
+}
+
+type leaderboard struct {
+	path   string
+	levels map[string][]LeaderboardEntry
+}
+
+// loadLeaderboard reads the leaderboard from the given file and verifies all
+// its entries, rejected is the number of entries that did not pass. A missing
+// file gives an empty leaderboard.
+func loadLeaderboard(path string) (board *leaderboard, rejected int, err error) {
+	board = &leaderboard{path: path, levels: make(map[string][]LeaderboardEntry)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return board, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var file leaderboardFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, 0, err
+	}
+	for name, entries := range file.Levels {
+		level := findLevel(name)
+		for _, entry := range entries {
+			if level != nil && verifyRun(level, toInputRecords(entry.Inputs), entry.Frames) {
+				board.insert(name, entry)
+			} else {
+				rejected++
+			}
+		}
+	}
+	return board, rejected, nil
+}
+
+// verifyRun replays the inputs for the hero and checks that he reaches the
+// goal exactly in the given frame.
+func verifyRun(level *Level, inputs []inputRecord, frames int) bool {
+	g := newHeadlessGame(level)
+	// Barney must not end the race before the hero gets to the goal
+	g.SetController(1, newReplayController(nil))
+	g.state = Playing
+	replay := newReplayController(inputs)
+	hero := g.racers[0]
+	for g.state == Playing && g.frame < frames {
+		for _, event := range replay.NextInputs(g, 0, g.frame) {
+			g.HandleInput(event)
+		}
+		g.Update()
+	}
+	return hero.finished && hero.finishFrame == frames
+}
+
+// insert adds the entry to the level's table if it is fast enough and returns
+// its rank, starting at 0.
+func (b *leaderboard) insert(level string, entry LeaderboardEntry) (rank int, ok bool) {
+	entries := b.levels[level]
+	rank = sort.Search(len(entries), func(i int) bool {
+		return entries[i].Frames > entry.Frames
+	})
+	if rank >= leaderboardSize {
+		return 0, false
+	}
+	entries = append(entries, LeaderboardEntry{})
+	copy(entries[rank+1:], entries[rank:])
+	entries[rank] = entry
+	if len(entries) > leaderboardSize {
+		entries = entries[:leaderboardSize]
+	}
+	b.levels[level] = entries
+	return rank, true
+}
+
+func (b *leaderboard) save() error {
+	data, err := json.MarshalIndent(&leaderboardFile{Levels: b.levels}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(b.path, data)
+}
+
+// SetLeaderboard makes every run that the player finishes a candidate for
+// the leaderboard, under the given name.
+func (g *Game) SetLeaderboard(board *leaderboard, playerName string) {
+	g.leaderboard = board
+	g.playerName = playerName
+}
+
+// Leaderboard returns the fastest runs on the current level, fastest first.
+func (g *Game) Leaderboard() []LeaderboardEntry {
+	if g.leaderboard == nil {
+		return nil
+	}
+	return g.leaderboard.levels[g.level.Name]
+}
+
+// addToLeaderboard is called when the player reaches the goal.
+func (g *Game) addToLeaderboard() {
+	if g.leaderboard == nil || g.versus || g.primaryCharIndex != 0 {
+		return
+	}
+	entry := LeaderboardEntry{
+		Name:   g.playerName,
+		Frames: g.frame,
+		Inputs: toSavedInputs(g.runRecorder.records),
+	}
+	if _, ok := g.leaderboard.insert(g.level.Name, entry); ok {
+		if err := g.leaderboard.save(); err != nil {
+			fmt.Println("error saving leaderboard:", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+// raceHero lets a path controller race the hero to the goal while the inputs
+// go through Game.HandleInput, like a player's. Barney stands still.
+func raceHero(t *testing.T, g *Game) {
+	hero := newPathController(g, 0)
+	g.SetController(1, newReplayController(nil))
+	g.state = Playing
+	for g.state == Playing && g.frame < 5000 {
+		for _, event := range hero.NextInputs(g, 0, g.frame) {
+			g.HandleInput(event)
+		}
+		g.Update()
+	}
+	if !g.racers[0].finished {
+		t.Fatal("the hero did not reach the goal")
+	}
+}
+
+func TestVerifyRun(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	raceHero(t, g)
+	inputs := g.runRecorder.records
+	frames := g.racers[0].finishFrame
+	if !verifyRun(&level1, inputs, frames) {
+		t.Fatal("the genuine run was rejected")
+	}
+
+	for _, c := range []struct {
+		name   string
+		inputs []inputRecord
+		frames int
+	}{
+		{"a faster time", inputs, frames - 1},
+		{"a slower time", inputs, frames + 1},
+		{"no inputs", nil, frames},
+		{"the first input left out", inputs[1:], frames},
+		{"the first jump left out", withoutFirstJump(inputs), frames},
+		{"the inputs one frame late", delayInputs(inputs, 1), frames},
+	} {
+		if verifyRun(&level1, c.inputs, c.frames) {
+			t.Errorf("the run with %s was accepted", c.name)
+		}
+	}
+}
+
+func delayInputs(inputs []inputRecord, frames int) []inputRecord {
+	delayed := make([]inputRecord, len(inputs))
+	for i, input := range inputs {
+		delayed[i] = inputRecord{input.frame + frames, input.event}
+	}
+	return delayed
+}
+
+// withoutFirstJump leaves out the first press and release of Jump.
+func withoutFirstJump(inputs []inputRecord) []inputRecord {
+	var result []inputRecord
+	pressed, released := false, false
+	for _, input := range inputs {
+		if input.event.Action == Jump && !released {
+			if !pressed && input.event.Pressed {
+				pressed = true
+				continue
+			}
+			if pressed && !input.event.Pressed {
+				released = true
+				continue
+			}
+		}
+		result = append(result, input)
+	}
+	return result
+}
@@ -380,15 +380,18 @@ func saveLevel() {
 		}
 	}
 
+	// only the geometry is generated, the rest of the level (start positions,
+	// goal etc.) is defined by hand in ../level1.go
 	buffer := bytes.NewBuffer(nil)
 	buffer.WriteString(`package main
 
-var level1 = Level{
-	[]LevelObject{` + objectsToString() + `},
-	[]LevelImage{` + imagesToString() + `},
-}
+var level1Objects = []LevelObject{
+` + objectsToString() + `}
+
+var level1Images = []LevelImage{
+` + imagesToString() + `}
 `)
-	ioutil.WriteFile("../level1.go", buffer.Bytes(), 0777)
+	ioutil.WriteFile("../level1_geometry.go", buffer.Bytes(), 0777)
 
 	saveImages()
 	saveObjects()
@@ -11,6 +11,37 @@ type LevelObject struct {
 }
 
 type Level struct {
+	Name string
+
+	// HeroStart and BarneyStart are the bottom-center points where the
+	// characters are placed at the start of the race
+	HeroStart    Point
+	BarneyStart  Point
+	Goal         Rectangle
+	CameraBounds Rectangle
+	// Music is the id of the track that plays during the race
+	Music string
+	// Flyover is the camera path that shows the level before the race, see
+	// camera_path.go
+	Flyover CameraPath
+	// Splits are checkpoints for timing runs, they must be reached in order
+	Splits []Split
+	// BarneyInputs are replayed for Barney during the race, they can either
+	// be recorded by hand (see recordingAI in main.go) or be generated with
+	// the route search (see route_search.go)
+	BarneyInputs []inputRecord
+
 	Objects []LevelObject
 	Images  []LevelImage
 }
+
+var levels = []*Level{&level1}
+
+func findLevel(name string) *Level {
+	for _, level := range levels {
+		if level.Name == name {
+			return level
+		}
+	}
+	return nil
+}
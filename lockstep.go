@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// Online versus uses deterministic lockstep: both games simulate the whole
+// race and only exchange the players' inputs. Inputs made in frame t are
+// applied in frame t+inputDelay on both sides so they have time to arrive. A
+// game only advances to the next frame once it knows the other player's
+// inputs for it. Every lockstepHashInterval frames, the games exchange a hash
+// of their state to detect if they got out of sync.
+
+const (
+	lockstepProtocolVersion = 2
+	lockstepHashInterval    = 60
+	// DefaultInputDelay is 60ms at 65 frames per second
+	DefaultInputDelay = 4
+)
+
+type lockstepMessageKind int
+
+const (
+	helloMessage lockstepMessageKind = iota + 1
+	inputsMessage
+	hashMessage
+	// byeMessage tells the other game that the player left the race
+	byeMessage
+)
+
+type lockstepMessage struct {
+	Kind lockstepMessageKind
+	// Frame is the frame that the inputs are applied in or, for a hash
+	// message, the frame before which the hash was computed
+	Frame  int
+	Events []InputEvent
+	Hash   uint32
+	// the host tells the other game how to set up the race in the hello
+	// message
+	Version    int
+	Level      string
+	Difficulty Difficulty
+	InputDelay int
+	// Racers is the number of racers, with all additional Barneys
+	Racers int
+}
+
+var (
+	errDesync          = errors.New("the games are out of sync")
+	errOtherPlayerLeft = errors.New("the other player left the race")
+)
+
+type lockstepSession struct {
+	game        *Game
+	transport   Transport
+	localIndex  int
+	remoteIndex int
+	inputDelay  int
+
+	// frame is the number of frames that were simulated, it is not reset
+	// when the level starts over
+	frame int
+	// pending are the local inputs that were not yet sent
+	pending []InputEvent
+	sent    bool
+	// the events to apply, by frame
+	local  map[int][]InputEvent
+	remote map[int][]InputEvent
+	// remoteKnown is the first frame for which the remote inputs have not yet
+	// arrived
+	remoteKnown  int
+	localHashes  map[int]uint32
+	remoteHashes map[int]uint32
+
+	received chan receivedMessage
+	// closed is closed when the session stops, it ends receiveLoop
+	closed chan struct{}
+	err    error
+}
+
+type receivedMessage struct {
+	msg lockstepMessage
+	err error
+}
+
+// hostLockstep starts an online race in which the local player is the hero
+// and the player on the other side of the transport is Barney. The host
+// decides the difficulty and input delay.
+func hostLockstep(g *Game, t Transport, inputDelay int) (*lockstepSession, error) {
+	err := t.Send(lockstepMessage{
+		Kind:       helloMessage,
+		Version:    lockstepProtocolVersion,
+		Level:      g.level.Name,
+		Difficulty: g.difficulty,
+		InputDelay: inputDelay,
+		Racers:     len(g.racers),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newLockstepSession(g, t, 0, 1, inputDelay), nil
+}
+
+// joinLockstep waits for the host's hello and starts an online race in which
+// the local player is Barney. The game must have been created for the same
+// level and with the same number of racers as the host's.
+func joinLockstep(g *Game, t Transport) (*lockstepSession, error) {
+	hello, err := t.Receive()
+	if err != nil {
+		return nil, err
+	}
+	if hello.Kind != helloMessage || hello.Version != lockstepProtocolVersion {
+		return nil, errors.New("the host uses a different version of the game")
+	}
+	if hello.Level != g.level.Name {
+		return nil, fmt.Errorf("the host plays level %q, not %q", hello.Level, g.level.Name)
+	}
+	if hello.Racers != len(g.racers) {
+		return nil, fmt.Errorf("the host races with %d racers, not %d",
+			hello.Racers, len(g.racers))
+	}
+	g.SetDifficulty(hello.Difficulty)
+	return newLockstepSession(g, t, 1, 0, hello.InputDelay), nil
+}
+
+func newLockstepSession(
+	g *Game,
+	t Transport,
+	localIndex, remoteIndex, inputDelay int,
+) *lockstepSession {
+	g.SetController(localIndex, nil)
+	g.SetController(remoteIndex, nil)
+	g.racers[remoteIndex].remote = true
+	g.SetVersus(true)
+	// both games start the race at the same time, skipping the menus, and
+	// both show the intro no matter what the players' settings are
+	g.startLevel(g.level, true)
+
+	s := &lockstepSession{
+		game:         g,
+		transport:    t,
+		localIndex:   localIndex,
+		remoteIndex:  remoteIndex,
+		inputDelay:   inputDelay,
+		local:        make(map[int][]InputEvent),
+		remote:       make(map[int][]InputEvent),
+		remoteKnown:  inputDelay, // nobody can make inputs for the first frames
+		localHashes:  make(map[int]uint32),
+		remoteHashes: make(map[int]uint32),
+		received:     make(chan receivedMessage, 64),
+		closed:       make(chan struct{}),
+	}
+	go s.receiveLoop()
+	return s
+}
+
+func (s *lockstepSession) receiveLoop() {
+	for {
+		msg, err := s.transport.Receive()
+		select {
+		case s.received <- receivedMessage{msg, err}:
+		case <-s.closed:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// HandleInput takes the local player's input, it is applied to the game
+// after the input delay. QuitGame quits right away and tells the other game.
+// There is no pause menu in an online race, the other game could not stop, so
+// Back does nothing.
+func (s *lockstepSession) HandleInput(event InputEvent) {
+	switch event.Action {
+	case GoLeft, GoRight, Jump:
+		event.CharacterIndex = s.localIndex
+		s.pending = append(s.pending, event)
+	case ToggleMinimap:
+		// the minimap is only shown locally
+		s.game.HandleInput(event)
+	case QuitGame:
+		if event.Pressed {
+			s.send(lockstepMessage{Kind: byeMessage})
+			s.game.HandleInput(InputEvent{QuitGame, true, s.localIndex})
+			s.Close()
+		}
+	}
+}
+
+// Update advances the game by one frame if the other player's inputs for it
+// have arrived. It returns false if the game has to wait or the session
+// stopped, see Err.
+func (s *lockstepSession) Update() bool {
+	if s.err != nil {
+		return false
+	}
+
+	// every frame's inputs are sent exactly once, even if there are none, so
+	// the other side knows that it may go on
+	if !s.sent {
+		inputFrame := s.frame + s.inputDelay
+		s.local[inputFrame] = s.pending
+		s.send(lockstepMessage{Kind: inputsMessage, Frame: inputFrame, Events: s.pending})
+		s.pending = nil
+		s.sent = true
+	}
+
+	s.receive()
+	if s.err != nil || s.frame >= s.remoteKnown {
+		return false
+	}
+
+	// apply the inputs in the same order on both sides
+	first, second := s.local[s.frame], s.remote[s.frame]
+	if s.remoteIndex < s.localIndex {
+		first, second = second, first
+	}
+	for _, event := range first {
+		s.game.HandleInput(event)
+	}
+	for _, event := range second {
+		s.game.HandleInput(event)
+	}
+	delete(s.local, s.frame)
+	delete(s.remote, s.frame)
+
+	s.game.Update()
+	s.frame++
+	s.sent = false
+
+	if s.frame%lockstepHashInterval == 0 {
+		hash := s.game.stateHash()
+		s.send(lockstepMessage{Kind: hashMessage, Frame: s.frame, Hash: hash})
+		s.localHashes[s.frame] = hash
+		s.compareHashes(s.frame)
+	}
+	return true
+}
+
+// receive handles all messages that arrived so far without waiting for more.
+func (s *lockstepSession) receive() {
+	for {
+		select {
+		case r := <-s.received:
+			if r.err != nil {
+				s.fail(r.err)
+				return
+			}
+			switch r.msg.Kind {
+			case inputsMessage:
+				events := r.msg.Events
+				// never trust the other side to only move its own character
+				for i := range events {
+					events[i].CharacterIndex = s.remoteIndex
+				}
+				s.remote[r.msg.Frame] = events
+				s.remoteKnown = r.msg.Frame + 1
+			case hashMessage:
+				s.remoteHashes[r.msg.Frame] = r.msg.Hash
+				s.compareHashes(r.msg.Frame)
+			case byeMessage:
+				s.fail(errOtherPlayerLeft)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *lockstepSession) compareHashes(frame int) {
+	local, haveLocal := s.localHashes[frame]
+	remote, haveRemote := s.remoteHashes[frame]
+	if !haveLocal || !haveRemote {
+		return
+	}
+	if local != remote {
+		s.fail(errDesync)
+	}
+	delete(s.localHashes, frame)
+	delete(s.remoteHashes, frame)
+}
+
+func (s *lockstepSession) send(msg lockstepMessage) {
+	if err := s.transport.Send(msg); err != nil {
+		s.fail(err)
+	}
+}
+
+func (s *lockstepSession) fail(err error) {
+	if s.err == nil {
+		s.err = err
+		close(s.closed)
+		s.transport.Close()
+	}
+}
+
+// Err returns the reason why the session stopped or nil if it is running.
+func (s *lockstepSession) Err() error {
+	return s.err
+}
+
+// Close stops the session and disconnects from the other player.
+func (s *lockstepSession) Close() {
+	s.fail(errors.New("the session was closed"))
+}
+
+// stateHash is a hash of everything that decides how the game goes on. Two
+// games in lockstep must have the same hash in the same frame.
+func (g *Game) stateHash() uint32 {
+	h := fnv.New32a()
+	var buf [8]byte
+	write := func(values ...int) {
+		for _, v := range values {
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+			h.Write(buf[:])
+		}
+	}
+	toInt := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	write(int(g.state), g.frame, g.cutScene.frame, g.prePlayCountDown,
+		g.playerDyingCountDown, g.versusWinCountDown, len(g.finishOrder))
+	for _, r := range g.racers {
+		c := r.character
+		write(c.Position.X, c.Position.Y, c.SpeedX, c.SpeedY, c.Direction,
+			toInt(c.InAir), c.Params.MaxSpeedX)
+		write(toInt(r.input.leftDown), toInt(r.input.rightDown),
+			toInt(r.input.jumpDown), toInt(r.input.mustJumpThisFrame))
+		write(r.frame, r.time, toInt(r.finished), r.respawnCountDown)
+	}
+	return h.Sum32()
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// startLockstep connects two headless games over an in-memory transport.
+// Both skip the intro and the flyover so the racers move in the first few
+// hundred frames.
+func startLockstep(t *testing.T) (host, join *lockstepSession) {
+	a, b := newMemoryTransports()
+	hostGame := newHeadlessGame(&level1)
+	hostGame.SetDifficulty(Hard)
+	host, err := hostLockstep(hostGame, a, DefaultInputDelay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	join, err = joinLockstep(newHeadlessGame(&level1), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []*Game{host.game, join.game} {
+		g.endCutScene()
+		g.cameraPath.stop()
+	}
+	return host, join
+}
+
+// runLockstep updates both sessions until they simulated the given number
+// of frames, inputs are made in the frames of the map.
+func runLockstep(
+	t *testing.T,
+	host, join *lockstepSession,
+	frames int,
+	inputs map[int][]InputEvent,
+) {
+	deadline := time.Now().Add(10 * time.Second)
+	for host.frame < frames || join.frame < frames {
+		if time.Now().After(deadline) {
+			t.Fatalf("the sessions got stuck at frames %d and %d", host.frame, join.frame)
+		}
+		for _, s := range []*lockstepSession{host, join} {
+			if s.frame >= frames {
+				continue
+			}
+			if !s.sent {
+				for _, event := range inputs[s.frame] {
+					if event.CharacterIndex == s.localIndex {
+						s.HandleInput(event)
+					}
+				}
+			}
+			s.Update()
+		}
+		if host.Err() != nil || join.Err() != nil {
+			return
+		}
+	}
+}
+
+func TestLockstepGamesStayInSync(t *testing.T) {
+	host, join := startLockstep(t)
+	const frames = 6 * lockstepHashInterval
+	runLockstep(t, host, join, frames, map[int][]InputEvent{
+		110: {{GoRight, true, 0}, {GoRight, true, 1}},
+		150: {{Jump, true, 0}},
+		165: {{Jump, false, 0}},
+		200: {{Jump, true, 1}},
+		230: {{Jump, false, 1}, {GoRight, false, 0}},
+		250: {{GoLeft, true, 1}},
+	})
+	if host.Err() != nil || join.Err() != nil {
+		t.Fatal(host.Err(), join.Err())
+	}
+	if host.game.stateHash() != join.game.stateHash() {
+		t.Error("the games' states differ")
+	}
+	for i, start := range []Point{level1.HeroStart, level1.BarneyStart} {
+		x, _ := host.game.racers[i].character.Position.Center()
+		if x == start.X {
+			t.Errorf("racer %d did not move", i)
+		}
+	}
+}
+
+func TestLockstepDetectsDesync(t *testing.T) {
+	host, join := startLockstep(t)
+	join.game.racers[0].character.Position.X++
+	runLockstep(t, host, join, 3*lockstepHashInterval, nil)
+	if host.Err() != errDesync && join.Err() != errDesync {
+		t.Fatal("the desync was not detected", host.Err(), join.Err())
+	}
+}
+
+func TestMemoryTransportAfterClose(t *testing.T) {
+	a, b := newMemoryTransports()
+	a.Close()
+	if err := a.Send(lockstepMessage{}); err != errTransportClosed {
+		t.Errorf("want errTransportClosed from Send but have %v", err)
+	}
+	if _, err := a.Receive(); err != errTransportClosed {
+		t.Errorf("want errTransportClosed from Receive but have %v", err)
+	}
+	if _, err := b.Receive(); err == nil {
+		t.Error("the other end still receives")
+	}
+}
+
+func TestLockstepBackDoesNotQuit(t *testing.T) {
+	host, join := startLockstep(t)
+	host.HandleInput(InputEvent{Back, true, 0})
+	host.HandleInput(InputEvent{Back, false, 0})
+	runLockstep(t, host, join, 10, nil)
+	if !host.game.Running() || host.Err() != nil || join.Err() != nil {
+		t.Fatal("Back stopped the online race", host.Err(), join.Err())
+	}
+	if host.game.state == Paused {
+		t.Error("Back paused the online race")
+	}
+}
+
+func TestLockstepQuitTellsTheOtherPlayer(t *testing.T) {
+	host, join := startLockstep(t)
+	runLockstep(t, host, join, 10, nil)
+	host.HandleInput(InputEvent{QuitGame, true, 0})
+	if host.game.Running() {
+		t.Error("the game did not quit")
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for join.Err() == nil && time.Now().Before(deadline) {
+		join.Update()
+	}
+	if join.Err() != errOtherPlayerLeft {
+		t.Errorf("want errOtherPlayerLeft but have %v", join.Err())
+	}
+}
+
+func TestJoinLockstepChecksTheRacerCount(t *testing.T) {
+	a, b := newMemoryTransports()
+	hostGame := newHeadlessGame(&level1)
+	hostGame.AddRacer(NewBarney(hostGame.assets), nil, level1.BarneyStart)
+	if _, err := hostLockstep(hostGame, a, DefaultInputDelay); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := joinLockstep(newHeadlessGame(&level1), b); err == nil {
+		t.Error("a game with fewer racers joined")
+	}
+}
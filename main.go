@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/gophergala2016/gophette/resource"
 	"github.com/veandco/go-sdl2/sdl"
@@ -10,7 +11,82 @@ import (
 	"unsafe"
 )
 
+var (
+	routeLevel = flag.String("route", "",
+		"search inputs for Barney on the level with this name and write them "+
+			"to -routefile instead of starting the game")
+	routeTarget = flag.Int("routetarget", 1200,
+		"the frame in which the searched route must reach the goal")
+	routeFile = flag.String("routefile", "./recorded_inputs.go",
+		"the Go file to write the searched route to")
+	routeVar = flag.String("routevar", "recordedInputs",
+		"the name of the variable for the searched route")
+	barneyAI = flag.Bool("barneyai", false,
+		"let Barney find his own way instead of replaying his recorded inputs")
+	barneyCount = flag.Int("barneys", 1,
+		"the number of Barneys in the race, all but the first find their own way")
+	versus = flag.Bool("versus", false,
+		"race against a second player who controls Barney with W, A and D")
+	splitHorizontal = flag.Bool("splithorizontal", false,
+		"in versus mode, split the screen into top and bottom instead of left "+
+			"and right")
+	difficultyName = flag.String("difficulty", "",
+		"how hard it is to beat Barney: easy, normal or hard, this overrides "+
+			"the difficulty from the settings")
+	hostAddr = flag.String("host", "",
+		"wait for another player to join an online race at this address, "+
+			"e.g. :7777")
+	joinAddr = flag.String("join", "",
+		"join the online race of the host at this address, e.g. localhost:7777")
+	inputDelay = flag.Int("inputdelay", DefaultInputDelay,
+		"when hosting an online race, the number of frames that inputs are "+
+			"delayed to hide the network latency")
+	timesPath = flag.String("times", configPath("times.json"),
+		"the file that keeps your best times and splits")
+	leaderboardPath = flag.String("leaderboard", configPath("leaderboard.json"),
+		"the file that keeps the fastest runs of all players")
+	playerName = flag.String("name", "Gophette",
+		"your name on the leaderboard")
+	settingsPath = flag.String("settings", configPath("settings.json"),
+		"the file that keeps your settings, they can be changed in the "+
+			"options menu")
+	zoom = flag.Float64("zoom", 1,
+		"how big the world is drawn, e.g. 2 shows a quarter of the level "+
+			"that 1 shows")
+	integerScale = flag.Bool("integerscale", false,
+		"only scale the game by whole numbers so all pixels have the same "+
+			"size, this leaves bigger black borders")
+)
+
 func main() {
+	flag.Parse()
+	if *routeLevel != "" {
+		generateRoute()
+		return
+	}
+	if *zoom <= 0 {
+		fmt.Println("the zoom must be greater than 0")
+		return
+	}
+
+	settings, err := loadSettings(*settingsPath)
+	if err != nil {
+		fmt.Println("error loading settings, using the defaults:", err)
+	}
+
+	// connect before opening the window, hosting waits for the other player
+	var transport Transport
+	if *hostAddr != "" {
+		fmt.Println("waiting for another player to join at", *hostAddr)
+		t, err := listenTCP(*hostAddr)
+		check(err)
+		transport = t
+	} else if *joinAddr != "" {
+		t, err := dialTCP(*joinAddr)
+		check(err)
+		transport = t
+	}
+
 	sdl.SetHint(sdl.HINT_RENDER_VSYNC, "1")
 
 	check(sdl.Init(sdl.INIT_EVERYTHING))
@@ -33,14 +109,22 @@ func main() {
 	check(err)
 	defer renderer.Destroy()
 	defer window.Destroy()
+	check(renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND))
 	window.SetTitle("Gophette's Adventures")
-	window.SetSize(800, 600)
+	window.SetSize(settings.WindowWidth, settings.WindowHeight)
 	sdl.ShowCursor(0)
 
-    window.SetFullscreen(sdl.WINDOW_FULLSCREEN_DESKTOP)
-	fullscreen := true
+	if settings.Fullscreen {
+		window.SetFullscreen(sdl.WINDOW_FULLSCREEN_DESKTOP)
+	}
 
-	camera := newWindowCamera(window.GetSize())
+	camera := newWindowCamera(VirtualScreenW, VirtualScreenH)
+	camera.setWindowSize(window.GetSize())
+	camera.setIntegerScale(*integerScale)
+	camera.setZoom(*zoom)
+	if *splitHorizontal {
+		camera.setSplitMode(SplitHorizontal)
+	}
 
 	assetLoader := newSDLAssetLoader(camera, renderer)
 	defer assetLoader.close()
@@ -52,90 +136,281 @@ func main() {
 
 	var charIndex int
 	const recordingAI = false // NOTE switch for development mode
-	if !recordingAI {
+	if *joinAddr != "" {
+		// the player joining an online race is Barney
+		charIndex = 1
+	} else if !recordingAI {
 		charIndex = 0
 	} else {
 		charIndex = 1
-		recordedInputs = recordedInputs[:0]
+		level1.BarneyInputs = nil
 		recordingInput = true
 	}
 
+	graphics := &sdlGraphics{renderer, camera, assetLoader.loadTexture("font")}
 	game := NewGame(
 		assetLoader,
-		&sdlGraphics{renderer, camera},
+		graphics,
 		camera,
+		&level1,
 		charIndex,
 	)
-
-	frameTime := time.Second / 65
-	lastUpdate := time.Now().Add(-frameTime)
-
-	music, err := mix.LoadMUS("./rsc/background_music.ogg")
+	game.ApplySettings(settings, *settingsPath)
+	if *difficultyName != "" {
+		difficulty, ok := parseDifficulty(*difficultyName)
+		if !ok {
+			fmt.Println("unknown difficulty:", *difficultyName)
+		}
+		game.SetDifficulty(difficulty)
+	}
+	if err := game.LoadTimes(*timesPath); err != nil {
+		fmt.Println("error loading times:", err)
+	}
+	board, rejected, err := loadLeaderboard(*leaderboardPath)
 	if err != nil {
-		fmt.Println("error loading music:", err)
+		fmt.Println("error loading leaderboard:", err)
 	} else {
-		defer music.Free()
-		music.FadeIn(-1, 500)
+		if rejected > 0 {
+			fmt.Println(rejected, "leaderboard entries were rejected, their runs "+
+				"do not reach the goal in the claimed time")
+		}
+		game.SetLeaderboard(board, *playerName)
+	}
+	if *barneyAI {
+		game.SetController(1, newPathController(game, 1))
+	}
+	if *versus {
+		game.SetController(1, nil)
+		game.SetVersus(true)
+	}
+	// a replay only works from the spawn it was recorded at so additional
+	// Barneys always find their own way
+	for i := 1; i < *barneyCount; i++ {
+		spawn := level1.BarneyStart
+		spawn.X += 60 * i
+		clone := game.AddRacer(NewBarney(assetLoader), nil, spawn)
+		game.SetController(clone, newPathController(game, clone))
+	}
+
+	// in an online race, the inputs go through the lockstep session which
+	// also updates the game
+	handleInput := game.HandleInput
+	var session *lockstepSession
+	if transport != nil {
+		if *hostAddr != "" {
+			session, err = hostLockstep(game, transport, *inputDelay)
+		} else {
+			session, err = joinLockstep(game, transport)
+		}
+		check(err)
+		handleInput = session.HandleInput
+	}
+
+	// the keyboard and all gamepads control the player, in local versus mode
+	// the second player uses other keys on the same keyboard
+	keyboard := &keyboardSource{}
+	secondKeyboard := &keyboardSource{secondPlayer: true}
+	gamepads := make(map[sdl.JoystickID]*sdlGamepad)
+	// windowW and windowH are the window's size when it is not fullscreen,
+	// they are saved when the game quits
+	fullscreen := settings.Fullscreen
+	windowW, windowH := settings.WindowWidth, settings.WindowHeight
+	defer func() { game.SetWindowSettings(fullscreen, windowW, windowH) }()
+	pollInputs := func(source InputSource, charIndex int, handleInput func(InputEvent)) {
+		for _, event := range source.NextInputs(game, charIndex, game.Frame()) {
+			if event.Action == ToggleFullscreen {
+				if event.Pressed {
+					if fullscreen {
+						window.SetFullscreen(0)
+					} else {
+						window.SetFullscreen(sdl.WINDOW_FULLSCREEN_DESKTOP)
+					}
+					fullscreen = !fullscreen
+					game.SetWindowSettings(fullscreen, windowW, windowH)
+				}
+				continue
+			}
+			handleInput(event)
+		}
 	}
 
+	frameTime := time.Second / FramesPerSecond
+	lastUpdate := time.Now().Add(-frameTime)
+
+	paused := false
 	for game.Running() {
 		for e := sdl.PollEvent(); e != nil; e = sdl.PollEvent() {
 			switch event := e.(type) {
 			case *sdl.KeyDownEvent:
 				if event.Repeat == 0 {
-					switch event.Keysym.Sym {
-					case sdl.K_LEFT:
-						game.HandleInput(InputEvent{GoLeft, true, charIndex})
-					case sdl.K_RIGHT:
-						game.HandleInput(InputEvent{GoRight, true, charIndex})
-					case sdl.K_UP:
-						game.HandleInput(InputEvent{Jump, true, charIndex})
-					case sdl.K_ESCAPE:
-						game.HandleInput(InputEvent{QuitGame, true, charIndex})
+					key := sdl.GetKeyName(event.Keysym.Sym)
+					if game.WaitingForKey() {
+						game.BindKey(key)
+					} else {
+						keyboard.keyChanged(key, true)
+						if *versus {
+							secondKeyboard.keyChanged(key, true)
+						}
 					}
 				}
 			case *sdl.KeyUpEvent:
-				switch event.Keysym.Sym {
-				case sdl.K_LEFT:
-					game.HandleInput(InputEvent{GoLeft, false, charIndex})
-				case sdl.K_RIGHT:
-					game.HandleInput(InputEvent{GoRight, false, charIndex})
-				case sdl.K_UP:
-					game.HandleInput(InputEvent{Jump, false, charIndex})
-				case sdl.K_F11:
-					if fullscreen {
-						window.SetFullscreen(0)
+				key := sdl.GetKeyName(event.Keysym.Sym)
+				keyboard.keyChanged(key, false)
+				if *versus {
+					secondKeyboard.keyChanged(key, false)
+				}
+			case *sdl.ControllerDeviceEvent:
+				if event.Type == sdl.CONTROLLERDEVICEADDED {
+					pad, err := openGamepad(int(event.Which))
+					if err != nil {
+						fmt.Println("error opening gamepad:", err)
 					} else {
-						window.SetFullscreen(sdl.WINDOW_FULLSCREEN_DESKTOP)
+						gamepads[pad.id] = pad
+					}
+				} else if event.Type == sdl.CONTROLLERDEVICEREMOVED {
+					// a remapped pad stays connected, only unplugging it
+					// closes it
+					if pad, ok := gamepads[event.Which]; ok {
+						// release whatever was held when it was unplugged
+						pad.source.disconnected()
+						pollInputs(pad.source, charIndex, handleInput)
+						pad.controller.Close()
+						delete(gamepads, event.Which)
+					}
+				}
+			case *sdl.ControllerAxisEvent:
+				if pad, ok := gamepads[event.Which]; ok {
+					if axis, ok := gamepadAxis(event.Axis); ok {
+						pad.source.axisMoved(axis, int(event.Value))
+					}
+				}
+			case *sdl.ControllerButtonEvent:
+				if pad, ok := gamepads[event.Which]; ok {
+					if button, ok := gamepadButton(event.Button); ok {
+						pad.source.buttonChanged(button, event.State == sdl.PRESSED)
 					}
-					fullscreen = !fullscreen
-				case sdl.K_ESCAPE:
-					game.HandleInput(InputEvent{QuitGame, false, charIndex})
 				}
 			case *sdl.WindowEvent:
 				if event.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
 					width, height := int(event.Data1), int(event.Data2)
 					camera.setWindowSize(width, height)
+					if !fullscreen {
+						windowW, windowH = width, height
+					}
 				}
 			case *sdl.QuitEvent:
-				game.HandleInput(InputEvent{QuitGame, true, charIndex})
+				handleInput(InputEvent{QuitGame, true, charIndex})
 			}
 		}
+		pollInputs(keyboard, charIndex, handleInput)
+		if *versus {
+			pollInputs(secondKeyboard, 1, game.HandleInput)
+		}
+		for _, pad := range gamepads {
+			pollInputs(pad.source, charIndex, handleInput)
+		}
 
 		now := time.Now()
 		dt := now.Sub(lastUpdate)
 		if dt > frameTime {
-			game.Update()
+			if session != nil {
+				session.Update()
+				if err := session.Err(); err != nil && game.Running() {
+					fmt.Println("online race stopped:", err)
+					game.HandleInput(InputEvent{QuitGame, true, charIndex})
+				}
+			} else {
+				game.Update()
+			}
 			lastUpdate = now
 		}
 
-		check(renderer.SetDrawColor(0, 95, 83, 255))
+		assetLoader.updateDucking()
+		if game.Paused() != paused {
+			paused = game.Paused()
+			if paused {
+				mix.PauseMusic()
+				mix.Pause(-1)
+			} else {
+				mix.ResumeMusic()
+				mix.Resume(-1)
+			}
+		}
+
+		// the window is black around the virtual screen
+		check(renderer.SetDrawColor(0, 0, 0, 255))
 		check(renderer.Clear())
+		graphics.ClearScreen(0, 95, 83)
 		game.Render()
 		renderer.Present()
 	}
 }
 
+type sdlGamepad struct {
+	id         sdl.JoystickID
+	controller *sdl.GameController
+	source     *gamepadSource
+}
+
+func openGamepad(deviceIndex int) (*sdlGamepad, error) {
+	controller := sdl.GameControllerOpen(deviceIndex)
+	if controller == nil {
+		return nil, sdl.GetError()
+	}
+	return &sdlGamepad{
+		id:         controller.GetJoystick().InstanceID(),
+		controller: controller,
+		source:     newGamepadSource(),
+	}, nil
+}
+
+func gamepadAxis(axis uint8) (GamepadAxis, bool) {
+	switch axis {
+	case sdl.CONTROLLER_AXIS_LEFTX:
+		return StickX, true
+	case sdl.CONTROLLER_AXIS_LEFTY:
+		return StickY, true
+	}
+	return 0, false
+}
+
+func gamepadButton(button uint8) (GamepadButton, bool) {
+	switch button {
+	case sdl.CONTROLLER_BUTTON_A:
+		return ButtonA, true
+	case sdl.CONTROLLER_BUTTON_B:
+		return ButtonB, true
+	case sdl.CONTROLLER_BUTTON_BACK:
+		return ButtonBack, true
+	case sdl.CONTROLLER_BUTTON_START:
+		return ButtonStart, true
+	case sdl.CONTROLLER_BUTTON_DPAD_LEFT:
+		return DPadLeft, true
+	case sdl.CONTROLLER_BUTTON_DPAD_RIGHT:
+		return DPadRight, true
+	case sdl.CONTROLLER_BUTTON_DPAD_UP:
+		return DPadUp, true
+	case sdl.CONTROLLER_BUTTON_DPAD_DOWN:
+		return DPadDown, true
+	}
+	return 0, false
+}
+
+func generateRoute() {
+	level := findLevel(*routeLevel)
+	if level == nil {
+		fmt.Println("unknown level:", *routeLevel)
+		return
+	}
+	route, err := searchBarneyRoute(level, *routeTarget)
+	if err != nil {
+		fmt.Println("error searching route:", err)
+		return
+	}
+	check(writeInputRecords(*routeFile, *routeVar, route))
+	fmt.Println("route written to", *routeFile)
+}
+
 func check(err error) {
 	if err != nil {
 		panic(err)
@@ -145,15 +420,32 @@ func check(err error) {
 type textureImage struct {
 	renderer *sdl.Renderer
 	camera   *windowCamera
-	texture  *sdl.Texture
+	// inWorld is false for images that are drawn in screen coordinates
+	inWorld bool
+	texture *sdl.Texture
 }
 
+// DrawAt draws the image scaled by the camera's zoom, if it is in the world,
+// and by the size of the window.
 func (img *textureImage) DrawAt(x, y int) {
-	_, _, w, h, err := img.texture.Query()
-	check(err)
-	dx, dy := img.camera.offset()
-	dest := sdl.Rect{int32(x + dx), int32(y + dy), w, h}
-	check(img.renderer.Copy(img.texture, nil, &dest))
+	w, h := img.Size()
+	dest := Rectangle{x, y, w, h}
+	if img.inWorld {
+		dest = img.camera.worldToScreenRect(dest)
+	}
+	dest = img.camera.toWindow(dest)
+	sdlDest := sdl.Rect{int32(dest.X), int32(dest.Y), int32(dest.W), int32(dest.H)}
+	check(img.renderer.Copy(img.texture, nil, &sdlDest))
+}
+
+func (img *textureImage) DrawAlphaAt(x, y int, alpha uint8) {
+	check(img.texture.SetAlphaMod(alpha))
+	img.DrawAt(x, y)
+	check(img.texture.SetAlphaMod(255))
+}
+
+func (img *textureImage) Destroy() {
+	img.texture.Destroy()
 }
 
 func (img *textureImage) Size() (int, int) {
@@ -162,12 +454,119 @@ func (img *textureImage) Size() (int, int) {
 	return int(w), int(h)
 }
 
+// the channels are split into a group for sound effects and one for voice
+// lines, each group has its own volume
+const (
+	effectChannels = 12
+	voiceChannels  = 4
+	effectsTag     = 1
+	voicesTag      = 2
+)
+
 type wavSound struct {
-	chunk *mix.Chunk
+	loader *sdlAssetLoader
+	chunk  *mix.Chunk
+	group  SoundGroup
 }
 
 func (s *wavSound) PlayOnce() {
-	s.chunk.Play(-1, 0)
+	s.Play(false)
+}
+
+func (s *wavSound) Play(loop bool) PlayingSound {
+	return s.play(centered, loop)
+}
+
+func (s *wavSound) PlayAt(p SoundPlacement) PlayingSound {
+	return s.play(p, false)
+}
+
+func (s *wavSound) play(p SoundPlacement, loop bool) PlayingSound {
+	tag := effectsTag
+	if s.group == VoiceSound {
+		tag = voicesTag
+	}
+	// if all channels are busy, the oldest sound is cut off
+	channel := mix.GroupAvailable(tag)
+	if channel == -1 {
+		channel = mix.GroupOldest(tag)
+	}
+	loops := 0
+	if loop {
+		loops = -1
+	}
+	volume := s.loader.volumes.soundVolume(s.group) * p.Volume / 100
+	channel, err := s.chunk.Play(channel, loops)
+	if err != nil {
+		return nullPlayingSound{}
+	}
+	// the placement stays with the channel until it plays the next sound
+	s.loader.placements[channel] = p
+	mix.Volume(channel, toMixVolume(volume))
+	left, right := p.speakers()
+	mix.SetPanning(channel, uint8(left*255/100), uint8(right*255/100))
+	playing := &wavPlayback{s.loader, channel}
+	s.loader.channels[channel] = playing
+	s.loader.updateDucking()
+	return playing
+}
+
+// wavPlayback is a sound playing on a channel. Once the channel plays
+// another sound, stopping this one does nothing.
+type wavPlayback struct {
+	loader  *sdlAssetLoader
+	channel int
+}
+
+func (p *wavPlayback) Stop() {
+	if p.Playing() {
+		mix.HaltChannel(p.channel)
+	}
+}
+
+func (p *wavPlayback) FadeOut(milliseconds int) {
+	if p.Playing() {
+		mix.FadeOutChannel(p.channel, milliseconds)
+	}
+}
+
+func (p *wavPlayback) Playing() bool {
+	return p.loader.channels[p.channel] == p && mix.Playing(p.channel) != 0
+}
+
+type oggMusic struct {
+	loader *sdlAssetLoader
+	music  *mix.Music
+}
+
+func (m *oggMusic) Play(fadeInMilliseconds int) {
+	m.loader.currentMusic = m
+	if err := m.music.FadeIn(-1, fadeInMilliseconds); err != nil {
+		fmt.Println("error playing music:", err)
+	}
+}
+
+func (m *oggMusic) PlayOnce() {
+	m.loader.currentMusic = m
+	if err := m.music.Play(1); err != nil {
+		fmt.Println("error playing music:", err)
+	}
+}
+
+func (m *oggMusic) Stop() {
+	if m.loader.currentMusic == m {
+		mix.HaltMusic()
+	}
+}
+
+func (m *oggMusic) FadeOut(milliseconds int) {
+	if m.loader.currentMusic == m {
+		mix.FadeOutMusic(milliseconds)
+	}
+}
+
+func toMixVolume(volume int) int {
+	return volume * mix.MAX_VOLUME / 100
 }
 
 type sdlAssetLoader struct {
@@ -175,18 +574,38 @@ type sdlAssetLoader struct {
 	renderer *sdl.Renderer
 	images   map[string]*textureImage
 	sounds   map[string]*wavSound
+	music    map[string]*oggMusic
+
+	volumes Volumes
+	// channels has the sound that was last started on each channel
+	channels     []*wavPlayback
+	placements   []SoundPlacement
+	currentMusic *oggMusic
+	// ducked is true while a voice line plays and the music is quieter
+	ducked bool
 }
 
 func newSDLAssetLoader(cam *windowCamera, renderer *sdl.Renderer) *sdlAssetLoader {
+	mix.AllocateChannels(effectChannels + voiceChannels)
+	mix.GroupChannels(0, effectChannels-1, effectsTag)
+	mix.GroupChannels(effectChannels, effectChannels+voiceChannels-1, voicesTag)
 	return &sdlAssetLoader{
-		camera:   cam,
-		renderer: renderer,
-		images:   make(map[string]*textureImage),
-		sounds:   make(map[string]*wavSound),
+		camera:     cam,
+		renderer:   renderer,
+		images:     make(map[string]*textureImage),
+		sounds:     make(map[string]*wavSound),
+		music:      make(map[string]*oggMusic),
+		volumes:    DefaultVolumes,
+		channels:   make([]*wavPlayback, effectChannels+voiceChannels),
+		placements: make([]SoundPlacement, effectChannels+voiceChannels),
 	}
 }
 
 func (l *sdlAssetLoader) LoadImage(id string) Image {
+	return l.loadTexture(id)
+}
+
+func (l *sdlAssetLoader) loadTexture(id string) *textureImage {
 	if img, ok := l.images[id]; ok {
 		return img
 	}
@@ -201,13 +620,13 @@ func (l *sdlAssetLoader) LoadImage(id string) Image {
 	defer surface.Free()
 	texture, err := l.renderer.CreateTextureFromSurface(surface)
 	check(err)
-	image := &textureImage{l.renderer, l.camera, texture}
+	image := &textureImage{l.renderer, l.camera, true, texture}
 	l.images[id] = image
 
 	return image
 }
 
-func (l *sdlAssetLoader) LoadSound(id string) Sound {
+func (l *sdlAssetLoader) LoadSound(id string, group SoundGroup) Sound {
 	if sound, ok := l.sounds[id]; ok {
 		return sound
 	}
@@ -219,12 +638,59 @@ func (l *sdlAssetLoader) LoadSound(id string) Sound {
 	rwOps := sdl.RWFromMem(unsafe.Pointer(&data[0]), len(data))
 	chunk, err := mix.LoadWAV_RW(rwOps, false)
 	check(err)
-	sound := &wavSound{chunk}
+	sound := &wavSound{l, chunk, group}
 	l.sounds[id] = sound
 
 	return sound
 }
 
+func (l *sdlAssetLoader) LoadMusic(id string) Music {
+	if music, ok := l.music[id]; ok {
+		return music
+	}
+	data := resource.Music[id]
+	if data == nil {
+		return nil
+	}
+
+	// the music is streamed from the data while it plays
+	rwOps := sdl.RWFromMem(unsafe.Pointer(&data[0]), len(data))
+	music, err := mix.LoadMUS_RW(rwOps, 0)
+	if err != nil {
+		// a broken track is not worth crashing for, the default track or
+		// silence is played instead
+		fmt.Println("error loading music", id+":", err)
+		return nil
+	}
+	m := &oggMusic{l, music}
+	l.music[id] = m
+
+	return m
+}
+
+func (l *sdlAssetLoader) SetVolumes(v Volumes) {
+	l.volumes = v
+	for channel := 0; channel < effectChannels+voiceChannels; channel++ {
+		group := EffectSound
+		if channel >= effectChannels {
+			group = VoiceSound
+		}
+		volume := v.soundVolume(group) * l.placements[channel].Volume / 100
+		mix.Volume(channel, toMixVolume(volume))
+	}
+	mix.VolumeMusic(toMixVolume(v.musicVolume(l.ducked)))
+}
+
+// updateDucking makes the music quieter while a voice line plays, it is
+// called every frame to notice when the voice line ends.
+func (l *sdlAssetLoader) updateDucking() {
+	ducked := mix.GroupNewer(voicesTag) != -1
+	if ducked != l.ducked {
+		l.ducked = ducked
+		mix.VolumeMusic(toMixVolume(l.volumes.musicVolume(ducked)))
+	}
+}
+
 func (l *sdlAssetLoader) close() {
 	for _, image := range l.images {
 		image.texture.Destroy()
@@ -232,21 +698,88 @@ func (l *sdlAssetLoader) close() {
 	for _, sound := range l.sounds {
 		sound.chunk.Free()
 	}
+	for _, music := range l.music {
+		music.music.Free()
+	}
 }
 
 type sdlGraphics struct {
 	renderer *sdl.Renderer
 	camera   *windowCamera
+	font     *textureImage
 }
 
 func (graphics *sdlGraphics) FillRect(rect Rectangle, r, g, b, a uint8) {
+	graphics.FillScreenRect(graphics.camera.worldToScreenRect(rect), r, g, b, a)
+}
+
+func (graphics *sdlGraphics) FillScreenRect(rect Rectangle, r, g, b, a uint8) {
 	check(graphics.renderer.SetDrawColor(r, g, b, a))
-	rect = rect.MoveBy(graphics.camera.offset())
+	rect = graphics.camera.toWindow(rect)
 	sdlRect := sdl.Rect{int32(rect.X), int32(rect.Y), int32(rect.W), int32(rect.H)}
 	graphics.renderer.FillRect(&sdlRect)
 }
 
+// ClearScreen only clears the virtual screen, the borders around it stay
+// black.
 func (graphics *sdlGraphics) ClearScreen(r, g, b uint8) {
-	check(graphics.renderer.SetDrawColor(r, g, b, 255))
-	graphics.renderer.Clear()
+	if graphics.camera.drawingToImage {
+		check(graphics.renderer.SetDrawColor(r, g, b, 255))
+		graphics.renderer.Clear()
+		return
+	}
+	w, h := graphics.ScreenSize()
+	graphics.FillScreenRect(Rectangle{0, 0, w, h}, r, g, b, 255)
+}
+
+func (graphics *sdlGraphics) DrawText(text string, x, y int, style TextStyle) {
+	font := defaultFont
+	texture := graphics.font.texture
+	font.draw(text, x, y, style, func(index, x, y int, color Color) {
+		check(texture.SetColorMod(color.R, color.G, color.B))
+		check(texture.SetAlphaMod(color.A))
+		src := sdl.Rect{
+			int32(index * font.glyphW), 0,
+			int32(font.glyphW), int32(font.glyphH),
+		}
+		r := graphics.camera.toWindow(Rectangle{x, y, font.glyphW, font.glyphH})
+		dest := sdl.Rect{int32(r.X), int32(r.Y), int32(r.W), int32(r.H)}
+		check(graphics.renderer.Copy(texture, &src, &dest))
+	})
+}
+
+func (graphics *sdlGraphics) TextSize(text string) (width, height int) {
+	return defaultFont.measure(text)
+}
+
+func (graphics *sdlGraphics) ScreenSize() (width, height int) {
+	return graphics.camera.screenW, graphics.camera.screenH
+}
+
+func (graphics *sdlGraphics) RenderToImage(width, height int, draw func()) Image {
+	texture, err := graphics.renderer.CreateTexture(
+		sdl.PIXELFORMAT_RGBA8888,
+		sdl.TEXTUREACCESS_TARGET,
+		width, height,
+	)
+	check(err)
+	check(texture.SetBlendMode(sdl.BLENDMODE_BLEND))
+	check(graphics.renderer.SetRenderTarget(texture))
+	check(graphics.renderer.SetDrawColor(0, 0, 0, 0))
+	check(graphics.renderer.Clear())
+	graphics.camera.drawingToImage = true
+	draw()
+	graphics.camera.drawingToImage = false
+	check(graphics.renderer.SetRenderTarget(nil))
+	return &textureImage{graphics.renderer, graphics.camera, false, texture}
+}
+
+func (graphics *sdlGraphics) SetClipRect(rect Rectangle) {
+	if rect.W <= 0 || rect.H <= 0 {
+		graphics.renderer.SetClipRect(nil)
+		return
+	}
+	rect = graphics.camera.toWindow(rect)
+	sdlRect := sdl.Rect{int32(rect.X), int32(rect.Y), int32(rect.W), int32(rect.H)}
+	graphics.renderer.SetClipRect(&sdlRect)
 }
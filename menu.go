@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The menus are game states like the race itself. They are controlled with
+// up (Jump) and GoDown to select an item, Confirm to activate it and Back to
+// return to the previous menu. In the options, left and right change the
+// selected setting.
+
+type menu struct {
+	title    string
+	items    []string
+	selected int
+}
+
+func (m *menu) move(delta int) {
+	m.selected = (m.selected + delta + len(m.items)) % len(m.items)
+}
+
+// the items of the main menu
+const (
+	playItem = iota
+	levelSelectItem
+	leaderboardItem
+	optionsItem
+	quitItem
+)
+
+// the items of the pause menu
+const (
+	resumeItem = iota
+	restartItem
+	quitToMenuItem
+)
+
+// the items of the options menu
+const (
+	difficultyItem = iota
+	masterVolumeItem
+	musicVolumeItem
+	effectsVolumeItem
+	voiceVolumeItem
+	introItem
+	controlsItem
+	optionsBackItem
+)
+
+// the controls menu lists boundActions, followed by these items
+const (
+	resetControlsItem = iota
+	controlsBackItem
+)
+
+func (g *Game) inMenu() bool {
+	return isMenu(g.state)
+}
+
+func isMenu(state GameState) bool {
+	return state == TitleScreen || state == MainMenu ||
+		state == LevelSelect || state == OptionsMenu || state == Paused ||
+		state == ControlsMenu || state == LeaderboardMenu
+}
+
+// pause freezes the race or intro and shows the pause menu.
+func (g *Game) pause() {
+	g.pausedState = g.state
+	g.state = Paused
+	g.menu = menu{
+		title: "Paused",
+		items: []string{"Resume", "Restart Level", "Quit to Menu"},
+	}
+}
+
+func (g *Game) resume() {
+	g.state = g.pausedState
+}
+
+func (g *Game) showMainMenu(selected int) {
+	g.state = MainMenu
+	g.menu = menu{
+		title:    "Gophette's Adventures",
+		items:    []string{"Play", "Level Select", "Leaderboard", "Options", "Quit"},
+		selected: selected,
+	}
+}
+
+// showLevelSelect lists the levels with the player's best times, the last
+// item goes back to the main menu.
+func (g *Game) showLevelSelect() {
+	g.state = LevelSelect
+	g.menu = menu{title: "Select Level"}
+	for _, level := range levels {
+		best := "-:--.--"
+		if run, ok := g.bestRuns[level.Name]; ok {
+			best = formatRaceTime(run.frames)
+		}
+		g.menu.items = append(g.menu.items, fmt.Sprintf("%-12s %s", level.Name, best))
+		if level == g.level {
+			g.menu.selected = len(g.menu.items) - 1
+		}
+	}
+	g.menu.items = append(g.menu.items, "Back")
+}
+
+// showLeaderboard lists the fastest runs on the current level, activating
+// any item goes back to the main menu.
+func (g *Game) showLeaderboard() {
+	g.state = LeaderboardMenu
+	g.menu = menu{title: "Leaderboard: " + g.level.Name}
+	for i, entry := range g.Leaderboard() {
+		g.menu.items = append(g.menu.items, fmt.Sprintf(
+			"%2d. %-20s %s", i+1, entry.Name, formatRaceTime(entry.Frames)))
+	}
+	if len(g.menu.items) == 0 {
+		g.menu.items = append(g.menu.items, "No runs yet")
+	}
+	g.menu.items = append(g.menu.items, "Back")
+	g.menu.selected = len(g.menu.items) - 1
+}
+
+func (g *Game) showOptions(selected int) {
+	g.state = OptionsMenu
+	g.menu = menu{
+		title: "Options",
+		items: []string{
+			"Difficulty: < " + g.difficulty.String() + " >",
+			fmt.Sprintf("Volume: < %d >", g.volumes.Master),
+			fmt.Sprintf("Music: < %d >", g.volumes.Music),
+			fmt.Sprintf("Effects: < %d >", g.volumes.Effects),
+			fmt.Sprintf("Voices: < %d >", g.volumes.Voice),
+			"Intro: < " + introSetting[g.skipIntro] + " >",
+			"Controls",
+			"Back",
+		},
+		selected: selected,
+	}
+}
+
+// showControls lists the actions with their keys.
+func (g *Game) showControls(selected int) {
+	g.state = ControlsMenu
+	g.menu = menu{title: "Controls", selected: selected}
+	for i, action := range boundActions {
+		keys := strings.Join(g.keyBindings.Keys(action), ", ")
+		if g.waitingForKey && i == selected {
+			keys = "press a key, " + cancelBindingKey + " cancels"
+		}
+		g.menu.items = append(g.menu.items,
+			fmt.Sprintf("%-10s %s", actionLabels[action], keys))
+	}
+	g.menu.items = append(g.menu.items, "Reset to Defaults", "Back")
+}
+
+var actionLabels = map[InputAction]string{
+	GoLeft:           "Left",
+	GoRight:          "Right",
+	Jump:             "Jump/Up",
+	GoDown:           "Down",
+	Confirm:          "Confirm",
+	Back:             "Back",
+	ToggleMinimap:    "Minimap",
+	ToggleFullscreen: "Fullscreen",
+}
+
+func (g *Game) handleMenuInput(event InputEvent) {
+	if g.state == Paused && !event.Pressed && event.CharacterIndex < len(g.racers) {
+		// keys that are let go during the pause must not stay down after
+		// resuming, this is the same as letting go right before pausing
+		g.racers[event.CharacterIndex].input.apply(event)
+		g.recordEvent(event)
+		return
+	}
+	if !event.Pressed || event.CharacterIndex != g.primaryCharIndex {
+		return
+	}
+
+	if g.state == TitleScreen {
+		if event.Action == Confirm {
+			g.showMainMenu(playItem)
+		}
+		return
+	}
+
+	switch event.Action {
+	case Jump:
+		g.menu.move(-1)
+	case GoDown:
+		g.menu.move(1)
+	case GoLeft, GoRight:
+		if g.state == OptionsMenu {
+			g.changeOption(event.Action == GoRight)
+		}
+	case Confirm:
+		g.activateMenuItem()
+	case Back:
+		if g.state == MainMenu {
+			g.state = TitleScreen
+		} else if g.state == LevelSelect {
+			g.showMainMenu(levelSelectItem)
+		} else if g.state == LeaderboardMenu {
+			g.showMainMenu(leaderboardItem)
+		} else if g.state == OptionsMenu {
+			g.showMainMenu(optionsItem)
+		} else if g.state == ControlsMenu {
+			g.showOptions(controlsItem)
+		} else if g.state == Paused {
+			g.resume()
+		}
+	}
+}
+
+func (g *Game) activateMenuItem() {
+	selected := g.menu.selected
+	switch g.state {
+	case MainMenu:
+		switch selected {
+		case playItem:
+			g.StartLevel(g.level)
+		case levelSelectItem:
+			g.showLevelSelect()
+		case leaderboardItem:
+			g.showLeaderboard()
+		case optionsItem:
+			g.showOptions(difficultyItem)
+		case quitItem:
+			g.quit()
+		}
+	case LeaderboardMenu:
+		g.showMainMenu(leaderboardItem)
+	case LevelSelect:
+		if selected < len(levels) {
+			g.StartLevel(levels[selected])
+		} else {
+			g.showMainMenu(levelSelectItem)
+		}
+	case Paused:
+		switch selected {
+		case resumeItem:
+			g.resume()
+		case restartItem:
+			g.resetLevel()
+		case quitToMenuItem:
+			g.showMainMenu(playItem)
+		}
+	case OptionsMenu:
+		switch selected {
+		case difficultyItem, masterVolumeItem, musicVolumeItem,
+			effectsVolumeItem, voiceVolumeItem, introItem:
+			g.changeOption(true)
+		case controlsItem:
+			g.showControls(0)
+		case optionsBackItem:
+			g.showMainMenu(optionsItem)
+		}
+	case ControlsMenu:
+		switch selected - len(boundActions) {
+		case resetControlsItem:
+			g.keyBindings = defaultKeyBindings()
+			g.secondKeyBindings = defaultSecondPlayerBindings()
+			g.saveSettings()
+			g.showControls(selected)
+		case controlsBackItem:
+			g.showOptions(controlsItem)
+		default:
+			g.waitingForKey = true
+			g.showControls(selected)
+		}
+	}
+}
+
+var introSetting = map[bool]string{false: "Show", true: "Skip"}
+
+// changeOption makes the selected setting in the options menu go up or down
+// and saves the settings.
+func (g *Game) changeOption(up bool) {
+	selected := g.menu.selected
+	volumes := g.volumes
+	switch selected {
+	case difficultyItem:
+		g.changeDifficulty(up)
+		g.settings.Difficulty = g.difficulty.String()
+	case introItem:
+		g.skipIntro = !g.skipIntro
+	case masterVolumeItem:
+		volumes.Master = changeVolume(volumes.Master, up)
+	case musicVolumeItem:
+		volumes.Music = changeVolume(volumes.Music, up)
+	case effectsVolumeItem:
+		volumes.Effects = changeVolume(volumes.Effects, up)
+	case voiceVolumeItem:
+		volumes.Voice = changeVolume(volumes.Voice, up)
+	default:
+		return
+	}
+	if volumes != g.volumes {
+		g.SetVolumes(volumes)
+	}
+	g.saveSettings()
+	g.showOptions(selected)
+}
+
+// changeDifficulty selects the next harder or easier difficulty, wrapping
+// around at the ends.
+func (g *Game) changeDifficulty(harder bool) {
+	d := g.difficulty + 1
+	if !harder {
+		d = g.difficulty - 1
+	}
+	if d > Hard {
+		d = Easy
+	}
+	if d < Easy {
+		d = Hard
+	}
+	g.SetDifficulty(d)
+}
+
+// changeVolume goes one step up or down, the volume stays between 0 and 100.
+func changeVolume(volume int, up bool) int {
+	if up {
+		return clamp(volume+volumeStep, 0, 100)
+	}
+	return clamp(volume-volumeStep, 0, 100)
+}
+
+func (g *Game) renderMenu(state GameState) {
+	g.graphics.ClearScreen(0, 95, 83)
+	w, h := g.graphics.ScreenSize()
+
+	if state == TitleScreen {
+		// show Gophette like at the end of the intro
+		x, y := 1000, 0
+		g.camera.CenterAround(x, y)
+		imgW, imgH := g.introGophette.Size()
+		g.introGophette.DrawAt(x-imgW/2, y-imgH/2)
+		g.graphics.DrawText("Gophette's Adventures", w/2, h/8, centeredText)
+		g.graphics.DrawText("Press Enter", w/2, h-h/6, centeredText)
+		return
+	}
+
+	g.renderMenuItems()
+}
+
+// renderPauseMenu shows the menu on top of the frozen race.
+func (g *Game) renderPauseMenu() {
+	g.render(g.pausedState)
+
+	w, h := g.graphics.ScreenSize()
+	g.graphics.FillScreenRect(Rectangle{0, 0, w, h}, 0, 0, 0, 128)
+	g.renderMenuItems()
+}
+
+func (g *Game) renderMenuItems() {
+	w, h := g.graphics.ScreenSize()
+	_, lineH := g.graphics.TextSize("X")
+	g.graphics.DrawText(g.menu.title, w/2, h/6, centeredText)
+	// the items are centered below the title, with less space between them
+	// if there are too many
+	top := h/6 + 2*lineH
+	space := h - top - lineH
+	step := lineH * 2
+	if n := len(g.menu.items); n*step > space {
+		step = space / n
+	}
+	y := top + (space-len(g.menu.items)*step)/2
+	for i, item := range g.menu.items {
+		style := centeredText
+		if i == g.menu.selected {
+			style.Color = selectedTextColor
+			item = "> " + item + " <"
+		}
+		g.graphics.DrawText(item, w/2, y, style)
+		y += step
+	}
+}
+
+var (
+	centeredText = TextStyle{
+		Align:        AlignCenter,
+		Color:        White,
+		Outline:      true,
+		OutlineColor: Black,
+	}
+	selectedTextColor = Color{255, 220, 60, 255}
+)
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestLeaderboardMenu(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.SetLeaderboard(&leaderboard{levels: map[string][]LeaderboardEntry{
+		level1.Name: {{Name: "Gophette", Frames: 2600}, {Name: "Barney", Frames: 3250}},
+	}}, "Gophette")
+
+	g.showMainMenu(leaderboardItem)
+	g.HandleInput(InputEvent{Confirm, true, 0})
+	if g.state != LeaderboardMenu {
+		t.Fatalf("want the leaderboard but have state %v", g.state)
+	}
+	want := []string{
+		" 1. Gophette             0:40.00",
+		" 2. Barney               0:50.00",
+		"Back",
+	}
+	if len(g.menu.items) != len(want) {
+		t.Fatalf("want items %q but have %q", want, g.menu.items)
+	}
+	for i := range want {
+		if g.menu.items[i] != want[i] {
+			t.Errorf("item %d: want %q but have %q", i, want[i], g.menu.items[i])
+		}
+	}
+
+	g.HandleInput(InputEvent{Confirm, true, 0})
+	if g.state != MainMenu || g.menu.selected != leaderboardItem {
+		t.Errorf("Back went to state %v item %d", g.state, g.menu.selected)
+	}
+}
+
+func TestReleasesDuringThePauseAreRecorded(t *testing.T) {
+	recordingInput = true
+	defer func() {
+		recordingInput = false
+		inputs.reset()
+	}()
+	inputs.reset()
+
+	g := newHeadlessGame(&level1)
+	g.state = Playing
+	for _, event := range []InputEvent{
+		{GoRight, true, 0},
+		{GoRight, true, recordedCharacterIndex},
+		{Back, true, 0},
+		{GoRight, false, 0},
+		{GoRight, false, recordedCharacterIndex},
+	} {
+		g.HandleInput(event)
+	}
+	if g.state != Paused {
+		t.Fatalf("the game is not paused, the state is %v", g.state)
+	}
+
+	for _, r := range []*inputRecorder{&g.runRecorder, &inputs} {
+		if len(r.records) != 2 || r.records[1].event.Pressed {
+			t.Errorf("want character %d's press and release recorded but have %v",
+				r.characterIndex, r.records)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+// The minimap shows the whole level scaled down in the top-left corner of
+// the screen. The level's objects and the goal never change so they are drawn
+// into an image once per level, only the racers are drawn every frame.
+
+const (
+	// minimapScale is how many level pixels make one pixel on the minimap
+	minimapScale       = 32
+	minimapMargin      = 16
+	minimapRacerSize   = 5
+	minimapBorderWidth = 2
+)
+
+// ToggleMinimap shows or hides the minimap.
+func (g *Game) ToggleMinimap() {
+	g.showMinimap = !g.showMinimap
+}
+
+// updateMinimap draws the current level's minimap image if it does not exist
+// yet, the last level's image is freed.
+func (g *Game) updateMinimap() {
+	if !g.showMinimap || g.minimapLevel == g.level {
+		return
+	}
+	if g.minimap != nil {
+		g.minimap.Destroy()
+	}
+	bounds := g.level.CameraBounds
+	w, h := bounds.W/minimapScale, bounds.H/minimapScale
+	g.minimap = g.graphics.RenderToImage(w, h, func() {
+		g.graphics.FillScreenRect(Rectangle{0, 0, w, h}, 0, 0, 0, 140)
+		for _, obj := range g.objects {
+			rect := g.toMinimap(obj.Bounds)
+			if obj.Solidness == Solid {
+				g.graphics.FillScreenRect(rect, 150, 120, 90, 255)
+			} else {
+				g.graphics.FillScreenRect(rect, 90, 200, 70, 255)
+			}
+		}
+		g.graphics.FillScreenRect(g.toMinimap(g.goalBounds), 255, 220, 60, 200)
+	})
+	g.minimapLevel = g.level
+}
+
+// toMinimap scales a rectangle in the level to the minimap, it is at least
+// one pixel in size so thin platforms do not vanish.
+func (g *Game) toMinimap(r Rectangle) Rectangle {
+	bounds := g.level.CameraBounds
+	scaled := Rectangle{
+		(r.X - bounds.X) / minimapScale,
+		(r.Y - bounds.Y) / minimapScale,
+		r.W / minimapScale,
+		r.H / minimapScale,
+	}
+	if scaled.W < 1 {
+		scaled.W = 1
+	}
+	if scaled.H < 1 {
+		scaled.H = 1
+	}
+	return scaled
+}
+
+func (g *Game) renderMinimap(viewport Rectangle) {
+	if !g.showMinimap || g.minimap == nil {
+		return
+	}
+	x, y := viewport.X+minimapMargin, viewport.Y+minimapMargin
+	w, h := g.minimap.Size()
+	g.graphics.FillScreenRect(
+		Rectangle{x, y, w, h}.AddMargin(minimapBorderWidth),
+		255, 255, 255, 200,
+	)
+	g.minimap.DrawAt(x, y)
+
+	// the markers go from the last racer to the first so that the first,
+	// usually the player, is not hidden behind a Barney
+	for i := len(g.racers) - 1; i >= 0; i-- {
+		cx, cy := g.racers[i].character.Position.Center()
+		pos := g.toMinimap(Rectangle{cx, cy, 0, 0})
+		pos.X, pos.Y = clamp(pos.X, 0, w-1), clamp(pos.Y, 0, h-1)
+		c := g.racerColor(i)
+		marker := Rectangle{
+			x + pos.X - minimapRacerSize/2,
+			y + pos.Y - minimapRacerSize/2,
+			minimapRacerSize,
+			minimapRacerSize,
+		}
+		g.graphics.FillScreenRect(marker, c.R, c.G, c.B, c.A)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+// The music follows the game state: the menus, the cut-scenes and every level
+// have their own track, winning plays a short stinger and losing fades the
+// music out. Only one track can play at a time so changing tracks fades the
+// old one out before the new one fades in.
+
+const (
+	menuMusicID    = "menu music"
+	victoryMusicID = "victory stinger"
+	// defaultMusicID is played for tracks that do not exist (yet)
+	defaultMusicID = "background music"
+
+	musicFadeMilliseconds = 500
+	musicFadeFrames       = musicFadeMilliseconds * FramesPerSecond / 1000
+)
+
+// musicTrack is what should be playing, an empty id is silence.
+type musicTrack struct {
+	id   string
+	loop bool
+}
+
+// musicForState returns the track for the current game state, ok is false if
+// the state does not change the music.
+func (g *Game) musicForState() (track musicTrack, ok bool) {
+	switch g.state {
+	case TitleScreen, MainMenu, LevelSelect, OptionsMenu, ControlsMenu,
+		LeaderboardMenu:
+		return musicTrack{menuMusicID, true}, true
+	case PlayingCutScene:
+		return musicTrack{g.cutScene.scene.Music, true}, true
+	case PrePlaying, Playing, PlayerDying:
+		return musicTrack{g.level.Music, true}, true
+	case PlayerWinning, VersusWinning:
+		return musicTrack{victoryMusicID, false}, true
+	case PlayerRealizingLoss, CameraShowsBarneyWinning:
+		return musicTrack{}, true
+	}
+	return musicTrack{}, false
+}
+
+// updateMusic starts fading to the current state's track if it changed and
+// starts the next track once the old one faded out.
+func (g *Game) updateMusic() {
+	if g.state == Paused {
+		return // the music is paused as well
+	}
+	if track, ok := g.musicForState(); ok && track != g.musicTrack {
+		g.musicTrack = track
+		g.changeMusic(track)
+	}
+
+	if g.musicFadeCountDown > 0 {
+		g.musicFadeCountDown--
+		if g.musicFadeCountDown == 0 {
+			g.startNextMusic()
+		}
+	}
+}
+
+func (g *Game) changeMusic(track musicTrack) {
+	next := g.loadMusic(track)
+	if next == g.music && track.loop && g.musicFadeCountDown == 0 {
+		return // the same track is already looping, e.g. the default track
+	}
+	g.nextMusic = next
+	g.nextMusicLoops = track.loop
+	if g.music == nil {
+		g.startNextMusic()
+		return
+	}
+	g.music.FadeOut(musicFadeMilliseconds)
+	g.musicFadeCountDown = musicFadeFrames
+}
+
+// loadMusic returns the track or, if it does not exist, the default track.
+// Stingers that do not exist are silent. The result is nil for silence.
+func (g *Game) loadMusic(track musicTrack) Music {
+	if track.id == "" {
+		return nil
+	}
+	if music := g.assets.LoadMusic(track.id); music != nil {
+		return music
+	}
+	if !track.loop {
+		return nil
+	}
+	return g.assets.LoadMusic(defaultMusicID)
+}
+
+func (g *Game) startNextMusic() {
+	g.music = g.nextMusic
+	g.nextMusic = nil
+	g.musicFadeCountDown = 0
+	if g.music == nil {
+		return
+	}
+	if g.nextMusicLoops {
+		g.music.Play(musicFadeMilliseconds)
+	} else {
+		g.music.PlayOnce()
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMusicCrossfadesBetweenStates(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	loader := recordEvents(g)
+	expect := func(when string, want ...musicEvent) {
+		t.Helper()
+		if !reflect.DeepEqual(loader.musicEvents, want) {
+			t.Fatalf("%s: want %v but have %v", when, want, loader.musicEvents)
+		}
+		loader.musicEvents = nil
+	}
+
+	g.Update()
+	expect("title screen", musicEvent{menuMusicID, "play"})
+
+	// the menu music fades out and the intro starts musicFadeFrames later,
+	// counting the frame that started the fade
+	g.StartLevel(&level1)
+	g.Update()
+	expect("intro starts", musicEvent{menuMusicID, "fade out"})
+	for i := 0; i < musicFadeFrames-2; i++ {
+		g.Update()
+	}
+	expect("intro fades in")
+	g.Update()
+	expect("intro plays", musicEvent{introScene.Music, "play"})
+
+	g.endIntro()
+	for i := 0; i < musicFadeFrames; i++ {
+		g.Update()
+	}
+	expect("race starts",
+		musicEvent{introScene.Music, "fade out"},
+		musicEvent{level1.Music, "play"},
+	)
+
+	// the victory stinger plays once, after the race music faded out
+	g.state = PlayerWinning
+	g.playerWinCountDown = PlayerWinDelay
+	for i := 0; i < musicFadeFrames; i++ {
+		g.Update()
+	}
+	expect("player wins",
+		musicEvent{level1.Music, "fade out"},
+		musicEvent{victoryMusicID, "play once"},
+	)
+}
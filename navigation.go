@@ -0,0 +1,361 @@
+package main
+
+import "container/heap"
+
+// The navigation graph describes where a character can go in a level. The
+// places to stand on are called segments, they are the tops of the level's
+// objects, split wherever a solid object is in the way. Segments are
+// connected by links, each link is a jump (or a walk off an edge) that was
+// simulated with the character's parameters and that lands on another
+// segment.
+
+const (
+	// navTakeOffStep is the horizontal distance between the simulated take
+	// off points on a segment
+	navTakeOffStep = 16
+	// navMaxFrames is the longest a simulated jump may take before landing
+	navMaxFrames = 150
+	// navMaxWalkFrames is how long to walk before giving up on walking off the
+	// edge of a segment
+	navMaxWalkFrames = 30
+)
+
+// these are the possible lengths for holding jump and holding the direction
+// while in the air that are tried for each take off point, 0 jump frames
+// means walking off the edge
+var (
+	navJumpFrames  = []int{0, 1, 6, 12, navMaxFrames}
+	navSteerFrames = []int{8, 16, navMaxFrames}
+)
+
+type navSegment struct {
+	// Y is the height of the ground, i.e. the bottom of a character standing
+	// on the segment
+	Y int
+	// MinX and MaxX are the range for the left of the character's collision
+	// rectangle while standing on the segment
+	MinX, MaxX int
+}
+
+type navLink struct {
+	from, to int
+	// takeOffX is the left of the character's collision rectangle when
+	// taking off, he must be running at full speed in direction dir
+	takeOffX int
+	dir      int
+	// jumpFrames is how long jump is held, 0 means walking off the edge,
+	// steerFrames is how long the direction is held
+	jumpFrames  int
+	steerFrames int
+	landX       int
+	frames      int
+}
+
+type navGraph struct {
+	segments []navSegment
+	links    []navLink
+	// linksFrom has the indices of all links starting at a segment
+	linksFrom [][]int
+	character Character
+	dieBounds Rectangle
+	goal      Rectangle
+	// runUp is the distance it takes to get from standing to full speed,
+	// accelFrames is the time it takes
+	runUp       int
+	accelFrames int
+}
+
+// newNavGraph creates the navigation for the given character in the game's
+// level.
+func newNavGraph(g *Game, char *Character) *navGraph {
+	n := &navGraph{
+		character: *char,
+		dieBounds: g.dieBounds,
+		goal:      g.goalBounds,
+	}
+	n.character.Reset(RightDirectionIndex)
+
+	params := char.Params
+	for speed := 0; speed < params.MaxSpeedX; {
+		speed += params.AccelerationX - params.DecelerationX
+		n.runUp += speed
+		n.accelFrames++
+	}
+
+	n.findSegments(g.objects)
+	n.linksFrom = make([][]int, len(n.segments))
+	for i := range n.segments {
+		n.findLinks(g, i)
+	}
+	return n
+}
+
+func (n *navGraph) findSegments(objects []CollisionObject) {
+	w, h := n.character.Position.W, n.character.Position.H
+	for i := range objects {
+		top := objects[i].Bounds
+		ranges := [][2]int{{top.X - w + 1, top.X + top.W - 1}}
+		for j := range objects {
+			other := objects[j].Bounds
+			if j != i && objects[j].Solidness == Solid &&
+				other.Y < top.Y && other.Y+other.H > top.Y-h {
+				ranges = cutRange(ranges, other.X-w+1, other.X+other.W-1)
+			}
+		}
+		for _, r := range ranges {
+			n.segments = append(n.segments, navSegment{top.Y, r[0], r[1]})
+		}
+	}
+}
+
+// cutRange removes the values from min to max (inclusive) from the ranges
+func cutRange(ranges [][2]int, min, max int) [][2]int {
+	var cut [][2]int
+	for _, r := range ranges {
+		if r[1] < min || r[0] > max {
+			cut = append(cut, r)
+			continue
+		}
+		if r[0] < min {
+			cut = append(cut, [2]int{r[0], min - 1})
+		}
+		if r[1] > max {
+			cut = append(cut, [2]int{max + 1, r[1]})
+		}
+	}
+	return cut
+}
+
+func (n *navGraph) findLinks(collider Collider, from int) {
+	seg := n.segments[from]
+	for _, dir := range []int{LeftDirectionIndex, RightDirectionIndex} {
+		// only take off where there is enough room to get to full speed and
+		// where a late take off (see isRobust) is still on the segment
+		late := n.character.Params.MaxSpeedX - 1
+		minX, maxX := seg.MinX, seg.MaxX
+		if dir == RightDirectionIndex {
+			minX += n.runUp
+			maxX -= late
+		} else {
+			maxX -= n.runUp
+			minX += late
+		}
+		for x := minX; x <= maxX; x += navTakeOffStep {
+			for _, jump := range navJumpFrames {
+				if jump == 0 && x+navTakeOffStep <= maxX && x > minX {
+					// walking off is only tried at the edges
+					continue
+				}
+				for _, steer := range navSteerFrames {
+					link, ok := n.simulate(collider, from, x, dir, jump, steer)
+					if ok && n.isRobust(collider, link) {
+						n.linksFrom[from] = append(n.linksFrom[from], len(n.links))
+						n.links = append(n.links, link)
+					}
+				}
+			}
+		}
+	}
+}
+
+// simulate returns the link that results from taking off at x on the given
+// segment. If the character does not land on another segment, ok is false.
+func (n *navGraph) simulate(
+	collider Collider,
+	from, x, dir, jumpFrames, steerFrames int,
+) (link navLink, ok bool) {
+	char := n.character
+	char.Position.X = x
+	char.Position.Y = n.segments[from].Y - char.Position.H
+	char.SpeedX = dirSign(dir) * char.Params.MaxSpeedX
+
+	var input inputState
+	input.mustJumpThisFrame = jumpFrames > 0
+	wasInAir := false
+	for frame := 1; frame <= navMaxFrames; frame++ {
+		input.jumpDown = frame <= jumpFrames
+		input.leftDown = dir == LeftDirectionIndex && frame <= steerFrames
+		input.rightDown = dir == RightDirectionIndex && frame <= steerFrames
+		moveCharacter(&char, &input, collider)
+
+		if char.InAir {
+			wasInAir = true
+			if !n.dieBounds.Overlaps(char.Position) {
+				return
+			}
+		} else if wasInAir {
+			to := n.segmentAt(char.Position)
+			if to == -1 || to == from {
+				return
+			}
+			return navLink{
+				from:        from,
+				to:          to,
+				takeOffX:    x,
+				dir:         dir,
+				jumpFrames:  jumpFrames,
+				steerFrames: steerFrames,
+				landX:       char.Position.X,
+				frames:      frame,
+			}, true
+		} else if frame > navMaxWalkFrames {
+			return
+		}
+	}
+	return
+}
+
+// isRobust checks that the link still lands on the same segment if the take
+// off is a little late. A running character moves several pixels per frame so
+// he will usually not hit the take off point exactly.
+func (n *navGraph) isRobust(collider Collider, link navLink) bool {
+	maxLate := n.character.Params.MaxSpeedX - 1
+	for _, late := range []int{maxLate / 2, maxLate} {
+		x := link.takeOffX + dirSign(link.dir)*late
+		late, ok := n.simulate(collider, link.from, x, link.dir, link.jumpFrames, link.steerFrames)
+		if !ok || late.to != link.to {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentAt returns the index of the segment that a character with the given
+// collision rectangle stands on or -1 if there is none.
+func (n *navGraph) segmentAt(bounds Rectangle) int {
+	for i, seg := range n.segments {
+		if seg.Y == bounds.Y+bounds.H && seg.MinX <= bounds.X && bounds.X <= seg.MaxX {
+			return i
+		}
+	}
+	return -1
+}
+
+// goalRange returns the range of x for which a character standing on the
+// segment is in the goal.
+func (n *navGraph) goalRange(segment int) (minX, maxX int, ok bool) {
+	seg := n.segments[segment]
+	h, w := n.character.Position.H, n.character.Position.W
+	if seg.Y-h < n.goal.Y || seg.Y > n.goal.Y+n.goal.H {
+		return 0, 0, false
+	}
+	minX, maxX = seg.MinX, seg.MaxX
+	if minX < n.goal.X {
+		minX = n.goal.X
+	}
+	if maxX > n.goal.X+n.goal.W-w {
+		maxX = n.goal.X + n.goal.W - w
+	}
+	return minX, maxX, minX <= maxX
+}
+
+// walkFrames estimates the time it takes to walk from x to the take off
+// point of the link and have full speed there.
+func (n *navGraph) walkFrames(x int, link *navLink) int {
+	speed := n.character.Params.MaxSpeedX
+	d := (link.takeOffX - x) * dirSign(link.dir)
+	if d >= n.runUp {
+		return d/speed + n.accelFrames/2
+	}
+	// not enough room to get to full speed, back up first
+	return (2*n.runUp-d)/speed + 2*n.accelFrames
+}
+
+// plan returns the links that lead from x on the given segment to the goal in
+// the shortest time. If there is no way to the goal or the segment is already
+// in the goal, plan returns nil.
+func (n *navGraph) plan(segment, x int) []int {
+	if _, _, ok := n.goalRange(segment); ok {
+		return nil
+	}
+
+	// the search states are the links, a state means having landed at the
+	// end of the link, the start is an extra state at the end
+	start := len(n.links)
+	cost := make([]int, len(n.links)+1)
+	previous := make([]int, len(n.links)+1)
+	for i := range cost {
+		cost[i] = -1
+	}
+	cost[start] = 0
+	previous[start] = -1
+
+	best, bestState := -1, -1
+	queue := &navQueue{{state: start}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(navQueueItem)
+		if item.cost != cost[item.state] {
+			continue // there is already a cheaper way to this state
+		}
+		if best != -1 && item.cost >= best {
+			break
+		}
+
+		seg, x := segment, x
+		if item.state != start {
+			seg, x = n.links[item.state].to, n.links[item.state].landX
+		}
+		if minX, maxX, ok := n.goalRange(seg); ok {
+			total := item.cost + (abs(clamp(x, minX, maxX)-x) / n.character.Params.MaxSpeedX)
+			if best == -1 || total < best {
+				best, bestState = total, item.state
+			}
+			continue
+		}
+
+		for _, next := range n.linksFrom[seg] {
+			link := &n.links[next]
+			c := item.cost + n.walkFrames(x, link) + link.frames
+			if cost[next] == -1 || c < cost[next] {
+				cost[next] = c
+				previous[next] = item.state
+				heap.Push(queue, navQueueItem{state: next, cost: c})
+			}
+		}
+	}
+
+	var plan []int
+	for state := bestState; state != -1 && state != start; state = previous[state] {
+		plan = append([]int{state}, plan...)
+	}
+	return plan
+}
+
+type navQueueItem struct {
+	state, cost int
+}
+
+type navQueue []navQueueItem
+
+func (q navQueue) Len() int { return len(q) }
+func (q navQueue) Less(i, j int) bool {
+	if q[i].cost == q[j].cost {
+		return q[i].state < q[j].state
+	}
+	return q[i].cost < q[j].cost
+}
+func (q navQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *navQueue) Push(x interface{}) { *q = append(*q, x.(navQueueItem)) }
+func (q *navQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func dirSign(dir int) int {
+	if dir == LeftDirectionIndex {
+		return -1
+	}
+	return 1
+}
+
+func clamp(x, min, max int) int {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestNavPlanLeadsToTheGoal(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	barney := g.racers[1].character
+	nav := newNavGraph(g, barney)
+	start := nav.segmentAt(barney.Position)
+	if start == -1 {
+		t.Fatal("Barney does not start on a segment")
+	}
+
+	plan := nav.plan(start, barney.Position.X)
+	if len(plan) == 0 {
+		t.Fatal("there is no plan to the goal")
+	}
+	if from := nav.links[plan[0]].from; from != start {
+		t.Errorf("the plan starts on segment %d, not %d", from, start)
+	}
+	for i := 1; i < len(plan); i++ {
+		if nav.links[plan[i-1]].to != nav.links[plan[i]].from {
+			t.Errorf("link %d of the plan does not start where link %d ends",
+				i, i-1)
+		}
+	}
+	if _, _, ok := nav.goalRange(nav.links[plan[len(plan)-1]].to); !ok {
+		t.Error("the plan does not end in the goal")
+	}
+}
+
+// racePathController lets Barney race with a pathController until the race
+// is over, after is called after every frame.
+func racePathController(t *testing.T, g *Game, after func()) {
+	g.state = Playing
+	for g.state == Playing && g.frame < 5000 {
+		g.Update()
+		after()
+	}
+	if len(g.finishOrder) != 1 || g.finishOrder[0] != 1 {
+		t.Fatalf("Barney did not reach the goal, the finish order is %v",
+			g.finishOrder)
+	}
+}
+
+func TestPathControllerReachesTheGoal(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.SetController(1, newPathController(g, 1))
+	racePathController(t, g, func() {})
+	if g.state != PlayerRealizingLoss {
+		t.Errorf("want the player to lose but the state is %v", g.state)
+	}
+}
+
+func TestPathControllerReplansAfterBeingMoved(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	c := newPathController(g, 1)
+	g.SetController(1, c)
+	barney := g.racers[1].character
+
+	// in the middle of the race, put Barney on a segment that his plan does
+	// not use, the next plan has to start from there
+	const moveFrame = 300
+	moved := -1
+	racePathController(t, g, func() {
+		if g.frame == moveFrame+1 {
+			if len(c.plan) == 0 || c.nav.links[c.plan[0]].from != moved {
+				t.Fatalf("Barney did not plan again from segment %d", moved)
+			}
+		}
+		if g.frame != moveFrame {
+			return
+		}
+		onPlan := map[int]bool{c.nav.segmentAt(barney.Position): true}
+		for _, link := range c.plan {
+			onPlan[c.nav.links[link].from] = true
+			onPlan[c.nav.links[link].to] = true
+		}
+		for i, seg := range c.nav.segments {
+			if !onPlan[i] && len(c.nav.plan(i, seg.MinX)) > 0 {
+				moved = i
+				barney.Reset(RightDirectionIndex)
+				barney.Position.X = seg.MinX
+				barney.Position.Y = seg.Y - barney.Position.H
+				return
+			}
+		}
+		t.Fatal("there is no segment off Barney's plan")
+	})
+	if moved == -1 {
+		t.Error("the race was over before Barney was moved")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+// pathController lets a character find his own way to the goal. It plans a
+// path over the level's navigation graph and follows it link by link. Every
+// time the character stands on a segment that is not the start of the next
+// link, e.g. because a jump went wrong or he was pushed away, a new path is
+// planned from where he is.
+type pathController struct {
+	nav *navGraph
+	// the navigation graph is built for a level and difficulty, it is built
+	// again if they change
+	level      *Level
+	difficulty Difficulty
+	plan       []int
+	// link is the index of the link that is currently being jumped, -1 while
+	// on the ground; airFrames counts the frames since taking off
+	link      int
+	airFrames int
+	wasInAir  bool
+	backingUp bool
+}
+
+// newPathController creates a controller for the character with the given
+// index. He will run with the maximum speed for the game's difficulty.
+func newPathController(g *Game, charIndex int) *pathController {
+	c := &pathController{link: -1}
+	c.buildNavGraph(g, charIndex)
+	return c
+}
+
+func (c *pathController) buildNavGraph(g *Game, charIndex int) {
+	r := g.racers[charIndex]
+	char := *r.character
+	char.Params.MaxSpeedX = r.defaultParams.MaxSpeedX +
+		g.difficulty.settings().maxSpeedXChange
+	c.nav = newNavGraph(g, &char)
+	c.level = g.level
+	c.difficulty = g.difficulty
+	c.Reset()
+}
+
+func (c *pathController) Reset() {
+	c.plan = nil
+	c.link = -1
+	c.backingUp = false
+}
+
+func (c *pathController) NextInputs(g *Game, charIndex, frame int) []InputEvent {
+	if c.level != g.level || c.difficulty != g.difficulty {
+		c.buildNavGraph(g, charIndex)
+	}
+	input, newJump := c.decide(g.racers[charIndex].character)
+
+	var events []InputEvent
+	state := g.racers[charIndex].input
+	change := func(action InputAction, was, is bool) {
+		if was != is {
+			events = append(events, InputEvent{action, is, charIndex})
+		}
+	}
+	change(GoLeft, state.leftDown, input.left)
+	change(GoRight, state.rightDown, input.right)
+	if newJump && state.jumpDown {
+		// jump has to be released before the next jump can start
+		change(Jump, true, false)
+		state.jumpDown = false
+	}
+	change(Jump, state.jumpDown, input.jump)
+	return events
+}
+
+// decide returns the buttons that the character should hold in this frame.
+// newJump is true if a jump starts in this frame.
+func (c *pathController) decide(char *Character) (input routeInput, newJump bool) {
+	if c.link != -1 {
+		link := &c.nav.links[c.link]
+		c.airFrames++
+		c.wasInAir = c.wasInAir || char.InAir
+		landed := c.wasInAir && !char.InAir
+		if !landed && c.airFrames <= link.frames+navMaxWalkFrames {
+			return c.linkInput(link), false
+		}
+		c.link = -1
+	}
+
+	if char.InAir {
+		return
+	}
+	segment := c.nav.segmentAt(char.Position)
+	if segment == -1 {
+		return
+	}
+	if len(c.plan) == 0 || c.nav.links[c.plan[0]].from != segment {
+		c.plan = c.nav.plan(segment, char.Position.X)
+		c.backingUp = false
+	}
+
+	x := char.Position.X
+	if len(c.plan) == 0 {
+		if minX, maxX, ok := c.nav.goalRange(segment); ok {
+			target := (minX + maxX) / 2
+			input.left = x > target
+			input.right = x < target
+		}
+		return
+	}
+
+	// run to the take off point and have full speed when reaching it, if
+	// there is not enough room to speed up, back up first
+	linkIndex := c.plan[0]
+	link := &c.nav.links[linkIndex]
+	sign := dirSign(link.dir)
+	maxSpeed := char.Params.MaxSpeedX
+	ahead := (link.takeOffX - x) * sign
+	speed := char.SpeedX * sign
+	seg := c.nav.segments[segment]
+	if c.backingUp {
+		// stop backing up in time before falling off the segment
+		room := x - seg.MinX
+		if link.dir == LeftDirectionIndex {
+			room = seg.MaxX - x
+		}
+		brake := char.Params.AccelerationX + char.Params.DecelerationX
+		brakingDistance := speed * speed / (2 * brake)
+		if ahead >= 2*c.nav.runUp || room <= brakingDistance+maxSpeed {
+			c.backingUp = false
+		}
+	} else {
+		if ahead <= 0 && ahead > -maxSpeed && speed == maxSpeed {
+			c.plan = c.plan[1:]
+			c.link = linkIndex
+			c.airFrames = 1
+			c.wasInAir = false
+			return c.linkInput(link), link.jumpFrames > 0
+		}
+		neededRoom := (maxSpeed*maxSpeed - speed*abs(speed)) / 2
+		if ahead <= 0 || ahead < neededRoom {
+			c.backingUp = true
+		}
+	}
+
+	forward := !c.backingUp
+	input.left = (link.dir == LeftDirectionIndex) == forward
+	input.right = (link.dir == RightDirectionIndex) == forward
+	return
+}
+
+func (c *pathController) linkInput(link *navLink) routeInput {
+	steer := c.airFrames <= link.steerFrames
+	return routeInput{
+		left:  steer && link.dir == LeftDirectionIndex,
+		right: steer && link.dir == RightDirectionIndex,
+		jump:  c.airFrames <= link.jumpFrames,
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+// A racer is one of the characters taking part in the race. Racers without a
+// controller are controlled by a player through Game.HandleInput.
+type racer struct {
+	character  *Character
+	input      inputState
+	controller Controller
+	spawn      Point
+	// defaultParams are the character's parameters when it was added, replays
+	// were recorded with them
+	defaultParams CharacterParams
+	// frame counts the racer's frames since his start, time is used to make
+	// his time run faster or slower, see difficulty.go
+	frame    int
+	time     int
+	finished bool
+	// finishFrame and splitFrames are the race times at the goal and the
+	// level's splits, see splits.go
+	finishFrame int
+	splitFrames []int
+	// respawnCountDown is greater than 0 after falling out of the level
+	respawnCountDown int
+	// remote racers are controlled by a player on another computer, see
+	// lockstep.go, the camera does not follow them
+	remote bool
+}
+
+// AddRacer adds a character to the race which starts with its bottom center
+// at spawn. Pass a nil controller for a character that is controlled by a
+// player. The returned index identifies the racer, e.g. in InputEvents.
+func (g *Game) AddRacer(char *Character, controller Controller, spawn Point) int {
+	char.SetBottomCenterTo(spawn.X, spawn.Y)
+	char.Direction = RightDirectionIndex
+	g.racers = append(g.racers, &racer{
+		character:     char,
+		controller:    controller,
+		spawn:         spawn,
+		defaultParams: char.Params,
+	})
+	return len(g.racers) - 1
+}
+
+// SetController replaces the controller of the racer with the given index.
+func (g *Game) SetController(racerIndex int, c Controller) {
+	g.racers[racerIndex].controller = c
+}
+
+// SetVersus turns the versus mode on or off. In versus mode, the players race
+// each other, give the racers that should be controlled by players a nil
+// controller.
+func (g *Game) SetVersus(versus bool) {
+	g.versus = versus
+}
+
+// FinishOrder returns the indices of the racers that reached the goal, in the
+// order in which they did.
+func (g *Game) FinishOrder() []int {
+	return g.finishOrder
+}
+
+func (g *Game) resetRacers() {
+	for _, r := range g.racers {
+		r.character.SetBottomCenterTo(r.spawn.X, r.spawn.Y)
+		r.character.Reset(RightDirectionIndex)
+		r.character.Params = r.defaultParams
+		if r.controller != nil {
+			r.controller.Reset()
+		}
+		r.frame = 0
+		r.time = 0
+		r.finished = false
+		r.finishFrame = 0
+		r.splitFrames = nil
+		r.respawnCountDown = 0
+	}
+	g.finishOrder = nil
+}
+
+// respawnFallenRacers lets the racers that fell out of the level start over
+// at their spawn after a delay. Only in single player, the player is not
+// respawned, the level starts over instead (see Game.Update).
+func (g *Game) respawnFallenRacers() {
+	for i, r := range g.racers {
+		if r.respawnCountDown > 0 || g.dieBounds.Overlaps(r.character.Position) {
+			continue
+		}
+		if i == g.primaryCharIndex && !g.versus {
+			continue
+		}
+		if r.controller == nil {
+			x, y := r.character.Position.Center()
+			g.playSoundAt(g.fallingSound, x, y)
+		}
+		r.respawnCountDown = RespawnDelay
+	}
+}
+
+func (g *Game) respawn(r *racer) {
+	r.character.SetBottomCenterTo(r.spawn.X, r.spawn.Y)
+	r.character.Reset(RightDirectionIndex)
+	// a replay has to start over from the spawn as well
+	if r.controller != nil {
+		r.controller.Reset()
+	}
+	r.frame = 0
+	r.time = 0
+}
+
+// updateRacers moves all racers for one frame, computer controlled racers
+// first get their inputs from their controllers.
+func (g *Game) updateRacers() {
+	for i, r := range g.racers {
+		if r.respawnCountDown > 0 {
+			r.respawnCountDown--
+			if r.respawnCountDown == 0 {
+				g.respawn(r)
+			}
+			continue
+		}
+		if r.controller == nil {
+			g.updateCharacter(i)
+			continue
+		}
+		for steps := g.racerSteps(r); steps > 0; steps-- {
+			for _, event := range r.controller.NextInputs(g, i, r.frame) {
+				g.HandleInput(event)
+			}
+			r.frame++
+			g.updateCharacter(i)
+		}
+	}
+}
+
+// checkFinishers adds all racers that just reached the goal to the finish
+// order.
+func (g *Game) checkFinishers() {
+	for i, r := range g.racers {
+		if !r.finished && g.goalBounds.Contains(r.character.Position) {
+			r.finished = true
+			r.finishFrame = g.frame
+			g.finishOrder = append(g.finishOrder, i)
+			g.finishSplits(i)
+			if i == g.primaryCharIndex {
+				g.completeRun()
+				g.addToLeaderboard()
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import "testing"
+
+// moveIntoGoal puts the racer's character in the middle of the goal.
+func moveIntoGoal(g *Game, racerIndex int) {
+	x, y := g.goalBounds.Center()
+	c := g.racers[racerIndex].character
+	c.SetBottomCenterTo(x, y+c.Position.H/2)
+}
+
+func TestFinishOrder(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.AddRacer(NewBarney(g.assets), nil, level1.BarneyStart)
+	g.SetController(1, nil)
+
+	g.frame = 100
+	moveIntoGoal(g, 2)
+	g.checkFinishers()
+	g.frame = 110
+	moveIntoGoal(g, 0)
+	g.checkFinishers()
+	g.frame = 120
+	g.checkFinishers()
+
+	order := g.FinishOrder()
+	if len(order) != 2 || order[0] != 2 || order[1] != 0 {
+		t.Fatalf("want the finish order [2 0] but have %v", order)
+	}
+	for i, want := range []int{110, 0, 100} {
+		if have := g.racers[i].finishFrame; have != want {
+			t.Errorf("racer %d: want finish frame %d but have %d", i, want, have)
+		}
+	}
+	if g.racers[1].finished {
+		t.Error("racer 1 finished without reaching the goal")
+	}
+}
+
+func TestVersusWinner(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.SetController(1, nil)
+	g.SetVersus(true)
+	g.state = Playing
+	g.Update()
+	moveIntoGoal(g, 1)
+	g.Update()
+
+	if g.state != VersusWinning {
+		t.Fatalf("want VersusWinning but the state is %v", g.state)
+	}
+	if g.winnerIndex != 1 {
+		t.Errorf("want racer 1 to win but the winner is %d", g.winnerIndex)
+	}
+	if len(g.finishOrder) != 1 || g.racers[0].finished {
+		t.Errorf("racer 0 finished too, the finish order is %v", g.finishOrder)
+	}
+}
+
+// fallOutOfLevel updates the game after the racer fell out of the level until
+// he should be back at his spawn.
+func fallOutOfLevel(t *testing.T, g *Game, racerIndex int) {
+	r := g.racers[racerIndex]
+	r.character.Position.Y = g.dieBounds.Y + g.dieBounds.H + 1000
+	g.Update()
+	if r.respawnCountDown != RespawnDelay {
+		t.Fatalf("racer %d did not fall, his respawn count down is %d",
+			racerIndex, r.respawnCountDown)
+	}
+	for i := 0; i < RespawnDelay; i++ {
+		g.Update()
+	}
+	if g.state != Playing {
+		t.Fatalf("want to keep playing but the state is %v", g.state)
+	}
+	x, _ := r.character.Position.Center()
+	y := r.character.Position.Y + r.character.Position.H
+	if x != r.spawn.X || y != r.spawn.Y || r.respawnCountDown != 0 {
+		t.Errorf("racer %d is at %d,%d after falling, not at his spawn %v",
+			racerIndex, x, y, r.spawn)
+	}
+	if r.frame != 0 {
+		t.Errorf("racer %d starts over at frame %d", racerIndex, r.frame)
+	}
+}
+
+func TestBarneyRespawnsAfterFalling(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	loader := recordEvents(g)
+	g.state = Playing
+	loader.soundEvents = nil
+	fallOutOfLevel(t, g, 1)
+	// only players hear themselves fall
+	if len(loader.soundEvents) != 0 {
+		t.Errorf("want no sound but have %v", loader.soundEvents)
+	}
+}
+
+func TestVersusPlayerRespawnsAfterFalling(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	loader := recordEvents(g)
+	g.SetController(1, nil)
+	g.SetVersus(true)
+	g.state = Playing
+	loader.soundEvents = nil
+	fallOutOfLevel(t, g, 0)
+	if len(loader.soundEvents) != 1 || loader.soundEvents[0].id != "fall" {
+		t.Errorf("want the falling sound but have %v", loader.soundEvents)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+// The route search generates the inputs for Barney so a level can have an
+// opponent without somebody having to record a run by hand.
+// It is a beam search over the inputs: every routeStepFrames frames each of
+// the candidate runs is continued with every possible input combination using
+// a headless game. Only the routeBeamWidth candidates closest to the goal are
+// kept for the next step. Candidates that end up in the same place with the
+// same speed are redundant, only one of them is kept.
+// Going straight for the goal often leads into dead ends, e.g. when Barney
+// has to go around a platform, so only a few candidates per area of the level
+// are kept. This keeps alternative routes alive even if they do not look
+// promising at first.
+
+const (
+	routeStepFrames   = 4
+	routeBeamWidth    = 500
+	routeCellSize     = 64
+	routeNodesPerCell = 4
+)
+
+type routeInput struct {
+	left, right, jump bool
+}
+
+var routeInputs = []routeInput{
+	{right: true},
+	{right: true, jump: true},
+	{left: true},
+	{left: true, jump: true},
+	{jump: true},
+	{},
+}
+
+type routeNode struct {
+	barney Character
+	input  inputState
+	frame  int
+	// time is Barney's left over time, see Game.racerSteps
+	time int
+	// distance is the estimated distance to the goal, smaller is better
+	distance int
+	// events are the inputs that were made at parent.frame and lead from the
+	// parent to this node
+	events []InputEvent
+	parent *routeNode
+}
+
+type routeNodeKey struct {
+	x, y           int
+	speedX, speedY int
+	jumpDown       bool
+}
+
+func (n *routeNode) key() routeNodeKey {
+	return routeNodeKey{
+		x:        n.barney.Position.X / 8,
+		y:        n.barney.Position.Y / 8,
+		speedX:   n.barney.SpeedX,
+		speedY:   n.barney.SpeedY,
+		jumpDown: n.input.jumpDown,
+	}
+}
+
+type routeSearch struct {
+	game *Game
+	hero Character
+}
+
+// searchBarneyRoute finds inputs that bring Barney from his start position to
+// the goal of the given level. The inputs are delayed so that he reaches the
+// goal exactly at targetFrame. If there is no route that is fast enough an
+// error is returned.
+func searchBarneyRoute(level *Level, targetFrame int) ([]inputRecord, error) {
+	game := newHeadlessGame(level)
+	game.SetController(1, newReplayController(nil))
+	search := routeSearch{game: game, hero: *game.racers[0].character}
+
+	beam := []*routeNode{{barney: *game.racers[1].character}}
+	for len(beam) > 0 && beam[0].frame < targetFrame {
+		var next []*routeNode
+		nodeIndex := make(map[routeNodeKey]int)
+		for _, node := range beam {
+			for _, input := range routeInputs {
+				n, reachedGoal := search.step(node, input)
+				if n == nil {
+					continue
+				}
+				if reachedGoal && n.frame <= targetFrame {
+					return n.records(targetFrame - n.frame), nil
+				}
+				key := n.key()
+				if i, ok := nodeIndex[key]; !ok {
+					nodeIndex[key] = len(next)
+					next = append(next, n)
+				} else if n.distance < next[i].distance {
+					next[i] = n
+				}
+			}
+		}
+
+		sort.Stable(byDistance(next))
+		beam = nil
+		nodesInCell := make(map[Point]int)
+		for _, n := range next {
+			cell := Point{
+				n.barney.Position.X / routeCellSize,
+				n.barney.Position.Y / routeCellSize,
+			}
+			if nodesInCell[cell] < routeNodesPerCell {
+				nodesInCell[cell]++
+				beam = append(beam, n)
+				if len(beam) == routeBeamWidth {
+					break
+				}
+			}
+		}
+	}
+	return nil, errors.New("no route found that reaches the goal in time")
+}
+
+// step simulates Barney for routeStepFrames frames with the given input,
+// starting at the given node. If he dies on the way, nil is returned. If he
+// reaches the goal, the returned node's frame is the one in which he did.
+func (s *routeSearch) step(from *routeNode, input routeInput) (*routeNode, bool) {
+	g := s.game
+	barney := g.racers[1]
+	*g.racers[0].character = s.hero
+	*barney.character = from.barney
+	barney.input = from.input
+	barney.frame = from.frame
+	barney.time = from.time
+	barney.finished = false
+	// a fall in an earlier step must not keep Barney waiting for his respawn
+	barney.respawnCountDown = 0
+	g.finishOrder = nil
+	g.state = Playing
+	g.frame = from.frame
+
+	next := &routeNode{parent: from}
+	press := func(action InputAction, was, is bool) {
+		if was != is {
+			event := InputEvent{action, is, 1}
+			next.events = append(next.events, event)
+			g.HandleInput(event)
+		}
+	}
+	press(GoLeft, from.input.leftDown, input.left)
+	press(GoRight, from.input.rightDown, input.right)
+	press(Jump, from.input.jumpDown, input.jump)
+
+	reachedGoal := false
+	for i := 0; i < routeStepFrames && !reachedGoal; i++ {
+		g.Update()
+		if !g.dieBounds.Overlaps(barney.character.Position) {
+			return nil, false
+		}
+		reachedGoal = barney.finished
+	}
+
+	next.barney = *barney.character
+	next.input = barney.input
+	next.frame = barney.frame
+	next.time = barney.time
+	x, y := next.barney.Position.Center()
+	goalX, goalY := g.goalBounds.Center()
+	// going up is harder than going sideways so vertical distance is rated
+	// higher
+	next.distance = abs(goalX-x) + 2*abs(goalY-y)
+	return next, reachedGoal
+}
+
+// records returns all events on the way to this node, delayed by the given
+// number of frames
+func (n *routeNode) records(delay int) []inputRecord {
+	var records []inputRecord
+	for ; n.parent != nil; n = n.parent {
+		for i := len(n.events) - 1; i >= 0; i-- {
+			records = append(records, inputRecord{n.parent.frame + delay, n.events[i]})
+		}
+	}
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records
+}
+
+type byDistance []*routeNode
+
+func (n byDistance) Len() int           { return len(n) }
+func (n byDistance) Less(i, j int) bool { return n[i].distance < n[j].distance }
+func (n byDistance) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestRouteSearchStepAfterFall(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.SetController(1, newReplayController(nil))
+	s := routeSearch{game: g, hero: *g.racers[0].character}
+	start := &routeNode{barney: *g.racers[1].character}
+
+	// a step that starts below the level makes Barney fall right away
+	fallen := &routeNode{barney: start.barney}
+	fallen.barney.Position.Y = g.dieBounds.Y + g.dieBounds.H + 100
+	if next, _ := s.step(fallen, routeInput{right: true}); next != nil {
+		t.Fatal("Barney did not fall")
+	}
+
+	next, _ := s.step(start, routeInput{right: true})
+	if next == nil {
+		t.Fatal("Barney fell from the start")
+	}
+	if next.frame != routeStepFrames {
+		t.Errorf("want frame %d after one step but have %d", routeStepFrames, next.frame)
+	}
+}
+
+func TestRouteSearchReplaysToTheGoal(t *testing.T) {
+	const target = 3000
+	records, err := searchBarneyRoute(&level1, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newHeadlessGame(&level1)
+	g.SetController(1, newReplayController(records))
+	g.state = Playing
+	for g.state == Playing && g.frame < target+100 {
+		g.Update()
+	}
+	if !g.racers[1].finished {
+		t.Fatalf("Barney did not reach the goal, he is at %v", g.racers[1].character.Position)
+	}
+}
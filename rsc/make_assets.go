@@ -6,9 +6,13 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/gonutz/xcf"
 	"github.com/nfnt/resize"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
 	"image"
 	"image/png"
 	"io/ioutil"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -149,12 +153,32 @@ func main() {
 	resources["intro pc 2"] = imageToBytes(scaleImageToFactor(intro.GetLayerByName("pc 2"), 0.67))
 	resources["intro gophette"] = imageToBytes(scaleImageToFactor(intro.GetLayerByName("gophette"), 0.67))
 
-	// the music file is too big, breaks IDE
-	/*
-		music, err := ioutil.ReadFile("./background_music.ogg")
+	fontImage, glyphW, glyphH := makeFont()
+	resources["font"] = imageToBytes(fontImage)
+	constants.WriteString(fmt.Sprintf(
+		"const (\n\tFontGlyphWidth  = %v\n\tFontGlyphHeight = %v\n"+
+			"\tFontFirstChar   = %v\n\tFontCharCount   = %v\n)\n",
+		glyphW, glyphH, fontFirstChar, fontCharCount,
+	))
+
+	// the music files are too big, having them in resources.go breaks the
+	// IDE so they go into their own file; tracks that do not exist yet are
+	// left out, the game plays the background music instead
+	music := make(ResourceMap)
+	for id, file := range map[string]string{
+		"background music": "background_music.ogg",
+		"menu music":       "menu_music.ogg",
+		"intro music":      "intro_music.ogg",
+		"victory stinger":  "victory_stinger.ogg",
+		"end music":        "end_music.ogg",
+	} {
+		data, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
 		check(err)
-		resources["music"] = music
-	*/
+		music[id] = data
+	}
 
 	for _, sound := range []string{
 		"win",
@@ -170,8 +194,45 @@ func main() {
 		resources[sound] = data
 	}
 
+	// the cut-scene scripts are text, the game parses them when it starts
+	for id, file := range map[string]string{
+		"intro scene": "intro_scene.txt",
+		"end scene":   "end_scene.txt",
+	} {
+		data, err := ioutil.ReadFile(file)
+		check(err)
+		resources[id] = data
+	}
+
 	content := toGoFile(resources, string(constants.Bytes()))
 	ioutil.WriteFile("../resource/resources.go", content, 0777)
+	ioutil.WriteFile("../resource/music.go", toMusicGoFile(music), 0777)
+}
+
+const (
+	fontFirstChar = 32
+	fontCharCount = 95
+	fontScale     = 2
+)
+
+// makeFont draws the printable ASCII characters in white, all in one row, and
+// scales them up without smoothing. The game colors them when drawing.
+func makeFont() (img image.Image, glyphW, glyphH int) {
+	face := basicfont.Face7x13
+	w, h := face.Advance, face.Height
+	atlas := image.NewNRGBA(image.Rect(0, 0, w*fontCharCount, h))
+	drawer := font.Drawer{Dst: atlas, Src: image.White, Face: face}
+	for i := 0; i < fontCharCount; i++ {
+		drawer.Dot = fixed.P(i*w, face.Ascent)
+		drawer.DrawString(string(rune(fontFirstChar + i)))
+	}
+	scaled := resize.Resize(
+		uint(fontScale*w*fontCharCount),
+		uint(fontScale*h),
+		atlas,
+		resize.NearestNeighbor,
+	)
+	return scaled, fontScale * w, fontScale * h
 }
 
 func imageToBytes(img image.Image) []byte {
@@ -226,7 +287,24 @@ func toGoFile(resources ResourceMap, constants string) []byte {
 type Rectangle struct{ X, Y, W, H int }
 
 ` + constants + `
-var Resources = map[string][]byte{`)
+var Resources = `)
+	writeResourceMap(buffer, resources)
+	return buffer.Bytes()
+}
+
+func toMusicGoFile(music ResourceMap) []byte {
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString(`package resource
+
+// NOTE this file is generated, do not edit it
+
+var Music = `)
+	writeResourceMap(buffer, music)
+	return buffer.Bytes()
+}
+
+func writeResourceMap(buffer *bytes.Buffer, resources ResourceMap) {
+	buffer.WriteString("map[string][]byte{")
 
 	var table sortableResourceEntries
 	for id, data := range resources {
@@ -247,7 +325,6 @@ var Resources = map[string][]byte{`)
 	}
 
 	buffer.WriteString("\n}\n")
-	return buffer.Bytes()
 }
 
 type resourceEntry struct {
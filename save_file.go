@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// The times file keeps the player's best run for every level between games,
+// including the inputs for the ghost (see ghost.go).
+
+type timesFile struct {
+	BestRuns map[string]savedRun
+}
+
+type savedRun struct {
+	Frames int
+	Splits []int
+	Inputs []savedInput
+}
+
+type savedInput struct {
+	Frame          int
+	Action         InputAction
+	Pressed        bool
+	CharacterIndex int
+}
+
+// LoadTimes reads the best runs from the given file and saves new best runs
+// to it. A missing file is not an error, it is created with the first best
+// run.
+func (g *Game) LoadTimes(path string) error {
+	g.timesPath = path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file timesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	for level, run := range file.BestRuns {
+		g.bestRuns[level] = bestRun{
+			frames: run.Frames,
+			splits: run.Splits,
+			inputs: toInputRecords(run.Inputs),
+		}
+	}
+	g.resetGhost()
+	return nil
+}
+
+func (g *Game) saveTimes() {
+	if g.timesPath == "" {
+		return
+	}
+	file := timesFile{BestRuns: make(map[string]savedRun)}
+	for level, run := range g.bestRuns {
+		file.BestRuns[level] = savedRun{
+			Frames: run.frames,
+			Splits: run.splits,
+			Inputs: toSavedInputs(run.inputs),
+		}
+	}
+	data, err := json.MarshalIndent(&file, "", "\t")
+	if err == nil {
+		err = writeConfigFile(g.timesPath, data)
+	}
+	if err != nil {
+		fmt.Println("error saving times:", err)
+	}
+}
+
+func toSavedInputs(records []inputRecord) []savedInput {
+	inputs := make([]savedInput, len(records))
+	for i, r := range records {
+		inputs[i] = savedInput{
+			Frame:          r.frame,
+			Action:         r.event.Action,
+			Pressed:        r.event.Pressed,
+			CharacterIndex: r.event.CharacterIndex,
+		}
+	}
+	return inputs
+}
+
+func toInputRecords(inputs []savedInput) []inputRecord {
+	records := make([]inputRecord, len(inputs))
+	for i, in := range inputs {
+		records[i] = inputRecord{
+			frame: in.Frame,
+			event: InputEvent{in.Action, in.Pressed, in.CharacterIndex},
+		}
+	}
+	return records
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// The settings are kept in the user's config directory so they are the same
+// no matter where the game is started from. They are loaded at startup and
+// saved whenever they are changed in the options menu. The window size is
+// saved when the game quits.
+
+type Settings struct {
+	Fullscreen bool
+	// WindowWidth and WindowHeight are the size of the window when it is not
+	// fullscreen
+	WindowWidth  int
+	WindowHeight int
+	Volumes      Volumes
+	Difficulty   string
+	SkipIntro    bool
+	Keys         keyBindingsFile
+}
+
+const (
+	minWindowWidth  = 320
+	minWindowHeight = 240
+)
+
+func defaultSettings() Settings {
+	return Settings{
+		Fullscreen:   true,
+		WindowWidth:  800,
+		WindowHeight: 600,
+		Volumes:      DefaultVolumes,
+		Difficulty:   Normal.String(),
+		Keys: keyBindingsFile{
+			Player1: defaultKeyBindings().toFile(),
+			Player2: defaultSecondPlayerBindings().toFile(),
+		},
+	}
+}
+
+// configPath returns where the file is kept in the user's config directory,
+// the settings, times and leaderboard files all go there. Without a config
+// directory the file is kept in the current directory.
+func configPath(file string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "./" + file
+	}
+	return filepath.Join(dir, "gophette", file)
+}
+
+// writeConfigFile writes the file and creates its directory if it does not
+// exist yet, e.g. when the game is started for the first time.
+func writeConfigFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0666)
+}
+
+// loadSettings reads the settings from the file, settings that the file does
+// not have keep their defaults. A missing file gives the defaults. If the
+// file cannot be read, the defaults are returned together with the error.
+func loadSettings(path string) (Settings, error) {
+	settings := defaultSettings()
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return defaultSettings(), err
+	}
+	settings.fix()
+	return settings, nil
+}
+
+// fix replaces values that the game cannot use, e.g. from editing the file by
+// hand, with the defaults.
+func (s *Settings) fix() {
+	defaults := defaultSettings()
+	if s.WindowWidth < minWindowWidth || s.WindowHeight < minWindowHeight {
+		s.WindowWidth, s.WindowHeight = defaults.WindowWidth, defaults.WindowHeight
+	}
+	for _, v := range []*int{
+		&s.Volumes.Master,
+		&s.Volumes.Music,
+		&s.Volumes.Effects,
+		&s.Volumes.Voice,
+	} {
+		*v = clamp(*v, 0, 100)
+	}
+	if _, ok := parseDifficulty(s.Difficulty); !ok {
+		s.Difficulty = defaults.Difficulty
+	}
+}
+
+func saveSettings(path string, s Settings) error {
+	data, err := json.MarshalIndent(&s, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(path, data)
+}
+
+// ApplySettings sets the game up with the settings, changes made in the
+// options menu are saved to the file at path.
+func (g *Game) ApplySettings(s Settings, path string) {
+	g.settings = s
+	g.settingsPath = path
+	g.SetVolumes(s.Volumes)
+	difficulty, _ := parseDifficulty(s.Difficulty)
+	g.SetDifficulty(difficulty)
+	g.skipIntro = s.SkipIntro
+	g.keyBindings = defaultKeyBindings()
+	g.keyBindings.setFromFile(s.Keys.Player1)
+	g.secondKeyBindings = defaultSecondPlayerBindings()
+	g.secondKeyBindings.setFromFile(s.Keys.Player2)
+}
+
+// Settings returns the settings as they were last saved or applied.
+func (g *Game) Settings() Settings {
+	return g.settings
+}
+
+// SetWindowSettings saves the window's state, width and height are the size
+// of the window when it is not fullscreen.
+func (g *Game) SetWindowSettings(fullscreen bool, width, height int) {
+	g.settings.Fullscreen = fullscreen
+	g.settings.WindowWidth = width
+	g.settings.WindowHeight = height
+	g.saveSettings()
+}
+
+// saveSettings saves the settings that can be changed in the options menu
+// together with the window settings.
+func (g *Game) saveSettings() {
+	g.settings.Volumes = g.volumes
+	g.settings.SkipIntro = g.skipIntro
+	g.settings.Keys = keyBindingsFile{
+		Player1: g.keyBindings.toFile(),
+		Player2: g.secondKeyBindings.toFile(),
+	}
+	if g.settingsPath == "" {
+		return
+	}
+	if err := saveSettings(g.settingsPath, g.settings); err != nil {
+		fmt.Println("error saving settings:", err)
+	}
+}
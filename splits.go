@@ -0,0 +1,182 @@
+package main
+
+import "fmt"
+
+// FramesPerSecond is how often the game is updated, race times are counted in
+// frames.
+const FramesPerSecond = 65
+
+// A Split is a region in a level, the time at which a racer first reaches it
+// is compared to earlier runs. The goal is the last split of every level.
+type Split struct {
+	Name   string
+	Region Rectangle
+}
+
+const goalSplitName = "goal"
+
+// A SplitResult is the player's time at a split.
+type SplitResult struct {
+	Name   string
+	Frames int
+	// PBDelta is the difference to the personal best in frames, a negative
+	// delta is faster
+	PBDelta int
+	HasPB   bool
+	// Rival names the other racers that the player is compared to, it is
+	// empty in versus mode where the results are only the player's own.
+	// RivalDelta is the difference to the fastest of them, if none of them
+	// reached the split yet, the player is ahead
+	Rival      string
+	RivalDelta int
+	HasRival   bool
+}
+
+// RaceTime is the player's time in frames, the timer starts when the race
+// starts and stops when the player reaches the goal.
+func (g *Game) RaceTime() int {
+	return g.raceTime(g.primaryCharIndex)
+}
+
+func (g *Game) raceTime(racerIndex int) int {
+	r := g.racers[racerIndex]
+	if r.finished {
+		return r.finishFrame
+	}
+	return g.frame
+}
+
+// SplitResults returns the player's results for the splits reached in the
+// current run.
+func (g *Game) SplitResults() []SplitResult {
+	return g.splitResults
+}
+
+// checkSplits records the time for racers that reach their next split.
+func (g *Game) checkSplits() {
+	splits := g.level.Splits
+	for i, r := range g.racers {
+		next := len(r.splitFrames)
+		if next < len(splits) && splits[next].Region.Overlaps(r.character.Position) {
+			r.splitFrames = append(r.splitFrames, g.frame)
+			if i == g.primaryCharIndex {
+				g.addSplitResult(next)
+			}
+		}
+	}
+}
+
+// finishSplits records the goal as the last split of a racer who just
+// finished, splits that he missed on the way get no time.
+func (g *Game) finishSplits(i int) {
+	r := g.racers[i]
+	for len(r.splitFrames) < len(g.level.Splits) {
+		r.splitFrames = append(r.splitFrames, -1)
+	}
+	r.splitFrames = append(r.splitFrames, g.frame)
+	if i == g.primaryCharIndex {
+		g.addSplitResult(len(g.level.Splits))
+	}
+}
+
+// lastSplitResult returns the racer's result at the last split that he
+// reached. Only the primary player's results are compared to his personal
+// best and to his rivals, in versus mode the other players only get times.
+func (g *Game) lastSplitResult(racerIndex int) (SplitResult, bool) {
+	if racerIndex == g.primaryCharIndex {
+		n := len(g.splitResults)
+		if n == 0 {
+			return SplitResult{}, false
+		}
+		return g.splitResults[n-1], true
+	}
+	frames := g.racers[racerIndex].splitFrames
+	for split := len(frames) - 1; split >= 0; split-- {
+		if frames[split] >= 0 {
+			return SplitResult{Name: g.splitName(split), Frames: frames[split]}, true
+		}
+	}
+	return SplitResult{}, false
+}
+
+func (g *Game) splitName(split int) string {
+	if split < len(g.level.Splits) {
+		return g.level.Splits[split].Name
+	}
+	return goalSplitName
+}
+
+func (g *Game) addSplitResult(split int) {
+	result := SplitResult{Name: g.splitName(split), Frames: g.frame}
+
+	if best, ok := g.bestRuns[g.level.Name]; ok &&
+		split < len(best.splits) && best.splits[split] >= 0 {
+		result.PBDelta = g.frame - best.splits[split]
+		result.HasPB = true
+	}
+
+	result.Rival = g.splitRival()
+	for i, r := range g.racers {
+		if result.Rival == "" {
+			break
+		}
+		if i == g.primaryCharIndex || split >= len(r.splitFrames) {
+			continue
+		}
+		other := r.splitFrames[split]
+		if other >= 0 && (!result.HasRival || g.frame-other > result.RivalDelta) {
+			result.RivalDelta = g.frame - other
+			result.HasRival = true
+		}
+	}
+
+	g.splitResults = append(g.splitResults, result)
+}
+
+func (s SplitResult) String() string {
+	text := s.Name + " " + formatRaceTime(s.Frames)
+	if s.HasPB {
+		text += " (" + formatDelta(s.PBDelta) + " to personal best)"
+	}
+	if s.Rival == "" {
+		return text
+	}
+	if s.HasRival {
+		text += " (" + formatDelta(s.RivalDelta) + " to " + s.Rival + ")"
+	} else {
+		text += " (ahead of " + s.Rival + ")"
+	}
+	return text
+}
+
+// splitRival names the racers that the player's splits are compared to.
+// Outside of versus mode, all others are Barneys.
+func (g *Game) splitRival() string {
+	switch {
+	case g.versus || len(g.racers) < 2:
+		return ""
+	case len(g.racers) == 2:
+		return "Barney"
+	}
+	return "the Barneys"
+}
+
+// formatRaceTime formats frames as minutes, seconds and hundredths, e.g.
+// 1:05.23
+func formatRaceTime(frames int) string {
+	hundredths := frames * 100 / FramesPerSecond
+	return fmt.Sprintf(
+		"%d:%02d.%02d",
+		hundredths/6000,
+		hundredths/100%60,
+		hundredths%100,
+	)
+}
+
+// formatDelta formats a time difference with its sign, e.g. -0:01.50
+func formatDelta(frames int) string {
+	if frames < 0 {
+		return "-" + formatRaceTime(-frames)
+	}
+	return "+" + formatRaceTime(frames)
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestSplitResultNamesTheRival(t *testing.T) {
+	g := newHeadlessGame(&level1)
+	g.frame = 100
+	g.addSplitResult(0)
+	g.racers[1].splitFrames = []int{90}
+	g.frame = 130
+	g.addSplitResult(0)
+	g.SetVersus(true)
+	g.addSplitResult(0)
+	g.SetVersus(false)
+	g.AddRacer(NewBarney(g.assets), nil, level1.BarneyStart)
+	g.addSplitResult(0)
+
+	for i, want := range []string{
+		"hills 0:01.53 (ahead of Barney)",
+		"hills 0:02.00 (+0:00.61 to Barney)",
+		"hills 0:02.00",
+		"hills 0:02.00 (+0:00.61 to the Barneys)",
+	} {
+		if have := g.splitResults[i].String(); have != want {
+			t.Errorf("result %d: want %q but have %q", i, want, have)
+		}
+	}
+}
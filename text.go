@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/gophergala2016/gophette/resource"
+	"strings"
+)
+
+// Text is drawn with a bitmap font that rsc/make_assets.go bakes into the
+// resources. The font image has the glyphs for the printable ASCII characters
+// in one row, all glyphs have the same size. The layout is done here so all
+// Graphics backends measure and place text the same way, a backend only has
+// to draw single glyphs.
+
+type Color struct {
+	R, G, B, A uint8
+}
+
+var (
+	White = Color{255, 255, 255, 255}
+	Black = Color{0, 0, 0, 255}
+)
+
+type TextAlign int
+
+const (
+	AlignLeft TextAlign = iota
+	AlignCenter
+	AlignRight
+)
+
+// TextStyle decides how text is drawn. The outline makes text readable on
+// top of the level, it is drawn around every glyph.
+type TextStyle struct {
+	Align        TextAlign
+	Color        Color
+	Outline      bool
+	OutlineColor Color
+}
+
+// DefaultTextStyle is white with a black outline.
+var DefaultTextStyle = TextStyle{
+	Color:        White,
+	Outline:      true,
+	OutlineColor: Black,
+}
+
+const fontOutlineWidth = 2
+
+type bitmapFont struct {
+	glyphW, glyphH int
+	first, count   int
+}
+
+var defaultFont = bitmapFont{
+	glyphW: resource.FontGlyphWidth,
+	glyphH: resource.FontGlyphHeight,
+	first:  resource.FontFirstChar,
+	count:  resource.FontCharCount,
+}
+
+// glyphIndex returns the index of the character's glyph in the font image,
+// characters that are not in the font are shown as '?'.
+func (f bitmapFont) glyphIndex(c byte) int {
+	i := int(c) - f.first
+	if i < 0 || i >= f.count {
+		return '?' - f.first
+	}
+	return i
+}
+
+// measure returns the size of the text, lines are separated by '\n'.
+func (f bitmapFont) measure(text string) (width, height int) {
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		if w := len(line) * f.glyphW; w > width {
+			width = w
+		}
+	}
+	return width, len(lines) * f.glyphH
+}
+
+// layout calls glyph for every character of the text with its glyph index and
+// its top-left position. The lines are aligned horizontally at x, y is the
+// top of the text.
+func (f bitmapFont) layout(text string, x, y int, align TextAlign, glyph func(index, x, y int)) {
+	for _, line := range strings.Split(text, "\n") {
+		left := x
+		if align == AlignCenter {
+			left -= len(line) * f.glyphW / 2
+		} else if align == AlignRight {
+			left -= len(line) * f.glyphW
+		}
+		for i := 0; i < len(line); i++ {
+			if line[i] != ' ' {
+				glyph(f.glyphIndex(line[i]), left+i*f.glyphW, y)
+			}
+		}
+		y += f.glyphH
+	}
+}
+
+// draw lays out the text and draws the outline and then the glyphs with
+// drawGlyph.
+func (f bitmapFont) draw(
+	text string,
+	x, y int,
+	style TextStyle,
+	drawGlyph func(index, x, y int, color Color),
+) {
+	if style.Outline {
+		d := fontOutlineWidth
+		for _, offset := range [][2]int{
+			{-d, -d}, {0, -d}, {d, -d},
+			{-d, 0}, {d, 0},
+			{-d, d}, {0, d}, {d, d},
+		} {
+			f.layout(text, x+offset[0], y+offset[1], style.Align, func(index, x, y int) {
+				drawGlyph(index, x, y, style.OutlineColor)
+			})
+		}
+	}
+	f.layout(text, x, y, style.Align, func(index, x, y int) {
+		drawGlyph(index, x, y, style.Color)
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testFont has the printable ASCII characters like the game's font but with
+// a simpler glyph size.
+var testFont = bitmapFont{glyphW: 10, glyphH: 20, first: 32, count: 95}
+
+func TestMeasureText(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		w, h int
+	}{
+		{"", 0, 20},
+		{"abc", 30, 20},
+		{"a c", 30, 20},
+		{"ab\ncdef", 40, 40},
+		{"abcd\nef\n", 40, 60},
+		{"\n\n", 0, 60},
+	} {
+		w, h := testFont.measure(c.text)
+		if w != c.w || h != c.h {
+			t.Errorf("%q: want %dx%d but have %dx%d", c.text, c.w, c.h, w, h)
+		}
+	}
+}
+
+func TestUnknownGlyphsAreQuestionMarks(t *testing.T) {
+	question := int('?') - testFont.first
+	for _, c := range []struct {
+		char byte
+		want int
+	}{
+		{' ', 0},
+		{'A', 'A' - 32},
+		{'~', '~' - 32},
+		{'\t', question},
+		{0x7f, question},
+		{0xe9, question},
+	} {
+		if have := testFont.glyphIndex(c.char); have != c.want {
+			t.Errorf("%q: want glyph %d but have %d", c.char, c.want, have)
+		}
+	}
+}
+
+type placedGlyph struct {
+	index, x, y int
+}
+
+func TestLayoutText(t *testing.T) {
+	const a, b, c = 'a' - 32, 'b' - 32, 'c' - 32
+	for _, test := range []struct {
+		align TextAlign
+		want  []placedGlyph
+	}{
+		{AlignLeft, []placedGlyph{
+			{a, 100, 50}, {b, 110, 50},
+			{c, 100, 70}, {a, 120, 70},
+		}},
+		{AlignCenter, []placedGlyph{
+			{a, 90, 50}, {b, 100, 50},
+			{c, 85, 70}, {a, 105, 70},
+		}},
+		{AlignRight, []placedGlyph{
+			{a, 80, 50}, {b, 90, 50},
+			{c, 70, 70}, {a, 90, 70},
+		}},
+	} {
+		// the space takes room but draws no glyph
+		var glyphs []placedGlyph
+		testFont.layout("ab\nc a", 100, 50, test.align, func(index, x, y int) {
+			glyphs = append(glyphs, placedGlyph{index, x, y})
+		})
+		if !reflect.DeepEqual(glyphs, test.want) {
+			t.Errorf("align %d: want %v but have %v", test.align, test.want, glyphs)
+		}
+	}
+}
+
+func TestOutlineIsDrawnBelowTheText(t *testing.T) {
+	var colors []Color
+	testFont.draw("ab", 0, 0, DefaultTextStyle, func(index, x, y int, c Color) {
+		colors = append(colors, c)
+	})
+	// 8 outline offsets and the text itself, each with both glyphs
+	if len(colors) != 9*2 {
+		t.Fatalf("want %d glyphs but have %d", 9*2, len(colors))
+	}
+	for i, c := range colors {
+		want := DefaultTextStyle.OutlineColor
+		if i >= 8*2 {
+			want = DefaultTextStyle.Color
+		}
+		if c != want {
+			t.Errorf("glyph %d: want color %v but have %v", i, want, c)
+		}
+	}
+
+	plain := DefaultTextStyle
+	plain.Outline = false
+	count := 0
+	testFont.draw("ab", 0, 0, plain, func(index, x, y int, c Color) { count++ })
+	if count != 2 {
+		t.Errorf("want 2 glyphs without an outline but have %d", count)
+	}
+}
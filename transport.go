@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// A Transport carries lockstep messages between two games, see lockstep.go.
+// Messages must arrive in the order in which they were sent.
+type Transport interface {
+	Send(msg lockstepMessage) error
+	// Receive blocks until the next message arrives. After the other side
+	// closed the transport, it returns io.EOF.
+	Receive() (lockstepMessage, error)
+	Close() error
+}
+
+// tcpTransport sends gob encoded messages over a TCP connection.
+type tcpTransport struct {
+	conn net.Conn
+	enc  *gob.Encoder
+	dec  *gob.Decoder
+}
+
+func newTCPTransport(conn net.Conn) *tcpTransport {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		// the messages are small and must not wait to be sent
+		tcp.SetNoDelay(true)
+	}
+	return &tcpTransport{
+		conn: conn,
+		enc:  gob.NewEncoder(conn),
+		dec:  gob.NewDecoder(conn),
+	}
+}
+
+// listenTCP waits for one other game to connect to the given address.
+func listenTCP(addr string) (*tcpTransport, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newTCPTransport(conn), nil
+}
+
+// dialTCP connects to a game that is waiting in listenTCP.
+func dialTCP(addr string) (*tcpTransport, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return newTCPTransport(conn), nil
+}
+
+func (t *tcpTransport) Send(msg lockstepMessage) error {
+	return t.enc.Encode(&msg)
+}
+
+func (t *tcpTransport) Receive() (msg lockstepMessage, err error) {
+	err = t.dec.Decode(&msg)
+	return
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
+
+var errTransportClosed = errors.New("the transport was closed")
+
+// memoryTransport connects two games in the same process, e.g. two headless
+// games in a test.
+type memoryTransport struct {
+	send    chan<- lockstepMessage
+	receive <-chan lockstepMessage
+	// closed is closed together with send, it also stops a Receive that is
+	// waiting; mutex keeps Send from sending on the closed channel
+	closed chan struct{}
+	mutex  sync.Mutex
+}
+
+// newMemoryTransports returns the two ends of an in-memory connection. Send
+// only blocks if the other end has not received the last memoryBufferSize
+// messages.
+func newMemoryTransports() (a, b Transport) {
+	const memoryBufferSize = 1024
+	aToB := make(chan lockstepMessage, memoryBufferSize)
+	bToA := make(chan lockstepMessage, memoryBufferSize)
+	return &memoryTransport{send: aToB, receive: bToA, closed: make(chan struct{})},
+		&memoryTransport{send: bToA, receive: aToB, closed: make(chan struct{})}
+}
+
+func (t *memoryTransport) Send(msg lockstepMessage) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.isClosed() {
+		return errTransportClosed
+	}
+	t.send <- msg
+	return nil
+}
+
+func (t *memoryTransport) Receive() (lockstepMessage, error) {
+	select {
+	case msg, ok := <-t.receive:
+		if !ok {
+			return msg, io.EOF
+		}
+		return msg, nil
+	case <-t.closed:
+		return lockstepMessage{}, errTransportClosed
+	}
+}
+
+func (t *memoryTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.isClosed() {
+		close(t.send)
+		close(t.closed)
+	}
+	return nil
+}
+
+func (t *memoryTransport) isClosed() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,41 +1,229 @@
 package main
 
-type windowCamera struct {
+import "math"
+
+// The game is drawn in a virtual screen of a fixed size, no matter how big
+// the window is, so everybody sees the same part of the level. The virtual
+// screen is scaled to fit the window, keeping its aspect ratio, and the rest
+// of the window stays black. With integer scaling, the scale is rounded down
+// to a whole number so pixels all have the same size. The zoom makes the
+// world bigger or smaller inside the virtual screen.
+const (
+	VirtualScreenW = 1280
+	VirtualScreenH = 720
+)
+
+// SplitMode decides how the window is divided when the camera shows two
+// views at once.
+type SplitMode int
+
+const (
+	// SplitVertical puts the views side by side
+	SplitVertical SplitMode = iota
+	// SplitHorizontal puts one view on top of the other
+	SplitHorizontal
+)
+
+type cameraView struct {
+	// position is the visible part of the world, viewport is where in the
+	// virtual screen it is shown, the viewport is the position's size times
+	// the zoom
 	position Rectangle
-	bounds   Rectangle
+	viewport Rectangle
+}
+
+type windowCamera struct {
+	screenW, screenH int
+	windowW, windowH int
+	integerScale     bool
+	// screenArea is where the virtual screen is shown in the window
+	screenArea Rectangle
+	// drawingToImage is set while drawing into an image, virtual screen
+	// coordinates are the image's pixels then
+	drawingToImage bool
+	zoom           float64
+	bounds         Rectangle
+	split          SplitMode
+	views          [2]cameraView
+	viewCount      int
+	// viewPoints are the indices of the points, passed to CenterViewsAround,
+	// that the views follow
+	viewPoints [2]int
+	// active is the view that is currently drawn
+	active int
 }
 
-func newWindowCamera(windowW, windowH int) *windowCamera {
+// newWindowCamera creates a camera for a virtual screen of the given size,
+// the window initially has the same size.
+func newWindowCamera(screenW, screenH int) *windowCamera {
 	cam := &windowCamera{
+		screenW: screenW,
+		screenH: screenH,
+		zoom:    1,
 		// initially set no bounds (big integers)
-		bounds: Rectangle{-999999, -999999, 2 * 999999, 2 * 999999},
+		bounds:    Rectangle{-999999, -999999, 2 * 999999, 2 * 999999},
+		viewCount: 1,
 	}
-	cam.setWindowSize(windowW, windowH)
+	cam.setWindowSize(screenW, screenH)
+	cam.layoutViews()
 	return cam
 }
 
 func (cam *windowCamera) setWindowSize(w, h int) {
-	cx, cy := cam.position.Center()
-	cam.position.W, cam.position.H = w, h
-	cam.CenterAround(cx, cy)
+	cam.windowW, cam.windowH = w, h
+	cam.fitScreen()
+}
+
+func (cam *windowCamera) setIntegerScale(on bool) {
+	cam.integerScale = on
+	cam.fitScreen()
+}
+
+// setZoom makes the world zoom times as big, i.e. less of it is visible.
+func (cam *windowCamera) setZoom(zoom float64) {
+	cam.zoom = zoom
+	cam.layoutViews()
+}
+
+// fitScreen places the biggest virtual screen that fits in the center of the
+// window.
+func (cam *windowCamera) fitScreen() {
+	scale := math.Min(
+		float64(cam.windowW)/float64(cam.screenW),
+		float64(cam.windowH)/float64(cam.screenH),
+	)
+	// a window that is too small for the virtual screen has to shrink it
+	if cam.integerScale && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	w := int(float64(cam.screenW) * scale)
+	h := int(float64(cam.screenH) * scale)
+	cam.screenArea = Rectangle{(cam.windowW - w) / 2, (cam.windowH - h) / 2, w, h}
+}
+
+// toWindow scales a rectangle from the virtual screen to window pixels.
+func (cam *windowCamera) toWindow(r Rectangle) Rectangle {
+	if cam.drawingToImage {
+		return r
+	}
+	// scaling both corners instead of the size makes neighboring rectangles
+	// touch without gaps
+	area := cam.screenArea
+	left := area.X + r.X*area.W/cam.screenW
+	top := area.Y + r.Y*area.H/cam.screenH
+	right := area.X + (r.X+r.W)*area.W/cam.screenW
+	bottom := area.Y + (r.Y+r.H)*area.H/cam.screenH
+	return Rectangle{left, top, right - left, bottom - top}
+}
+
+func (cam *windowCamera) setSplitMode(split SplitMode) {
+	cam.split = split
+	cam.layoutViews()
+}
+
+// layoutViews places the viewports in the virtual screen and keeps the views
+// centered where they were before.
+func (cam *windowCamera) layoutViews() {
+	w, h := cam.screenW, cam.screenH
+	viewports := []Rectangle{{0, 0, w, h}}
+	if cam.viewCount == 2 {
+		if cam.split == SplitVertical {
+			viewports = []Rectangle{{0, 0, w / 2, h}, {w / 2, 0, w - w/2, h}}
+		} else {
+			viewports = []Rectangle{{0, 0, w, h / 2}, {0, h / 2, w, h - h/2}}
+		}
+	}
+	for i, viewport := range viewports {
+		view := &cam.views[i]
+		cx, cy := view.position.Center()
+		view.viewport = viewport
+		view.position.W = int(float64(viewport.W) / cam.zoom)
+		view.position.H = int(float64(viewport.H) / cam.zoom)
+		view.centerAround(cx, cy, cam.bounds)
+	}
+}
+
+func (cam *windowCamera) setViewCount(n int) {
+	if cam.viewCount != n {
+		cam.viewCount = n
+		cam.layoutViews()
+	}
+	cam.active = 0
 }
 
 func (cam *windowCamera) CenterAround(x, y int) {
-	cam.position.X = x - cam.position.W/2
-	cam.position.Y = y - cam.position.H/2
+	cam.setViewCount(1)
+	cam.viewPoints = [2]int{0, 0}
+	cam.views[0].centerAround(x, y, cam.bounds)
+}
+
+// CenterViewsAround splits the window into two views, each centered around
+// one of the first two points. If the points are close enough together, a
+// single view is used instead. The views only merge again when the points are
+// clearly closer than where they split, otherwise players that stay near that
+// distance would make the screen flicker between one and two views.
+func (cam *windowCamera) CenterViewsAround(points []Point) {
+	if len(points) == 0 {
+		return
+	}
+	a, b := points[0], points[0]
+	if len(points) > 1 {
+		b = points[1]
+	}
+	visibleW := int(float64(cam.screenW) / cam.zoom)
+	visibleH := int(float64(cam.screenH) / cam.zoom)
+	// split at more than 1/2 of the visible size, merge at less than 3/8
+	maxW, maxH := visibleW/2, visibleH/2
+	if cam.viewCount == 2 {
+		maxW, maxH = visibleW*3/8-1, visibleH*3/8-1
+	}
+	if abs(a.X-b.X) <= maxW && abs(a.Y-b.Y) <= maxH {
+		cam.CenterAround((a.X+b.X)/2, (a.Y+b.Y)/2)
+		return
+	}
+
+	// the left (or upper) point goes into the left (or upper) view so the
+	// views look like one when they merge
+	cam.viewPoints = [2]int{0, 1}
+	if cam.split == SplitVertical && a.X > b.X ||
+		cam.split == SplitHorizontal && a.Y > b.Y {
+		a, b = b, a
+		cam.viewPoints = [2]int{1, 0}
+	}
+	cam.setViewCount(2)
+	cam.views[0].centerAround(a.X, a.Y, cam.bounds)
+	cam.views[1].centerAround(b.X, b.Y, cam.bounds)
+}
+
+func (cam *windowCamera) ViewCount() int {
+	return cam.viewCount
+}
+
+func (cam *windowCamera) ViewPoint(i int) int {
+	return cam.viewPoints[i]
+}
+
+func (cam *windowCamera) SelectView(i int) (viewport Rectangle) {
+	cam.active = i
+	return cam.views[i].viewport
+}
+
+func (view *cameraView) centerAround(x, y int, bounds Rectangle) {
+	view.position.X = x - view.position.W/2
+	view.position.Y = y - view.position.H/2
 
 	// keep the camera in the bounds
-	if cam.position.X < cam.bounds.X {
-		cam.position.X = cam.bounds.X
+	if view.position.X < bounds.X {
+		view.position.X = bounds.X
 	}
-	if cam.position.Y < cam.bounds.Y {
-		cam.position.Y = cam.bounds.Y
+	if view.position.Y < bounds.Y {
+		view.position.Y = bounds.Y
 	}
-	if cam.position.X+cam.position.W > cam.bounds.X+cam.bounds.W {
-		cam.position.X = cam.bounds.X + cam.bounds.W - cam.position.W
+	if view.position.X+view.position.W > bounds.X+bounds.W {
+		view.position.X = bounds.X + bounds.W - view.position.W
 	}
-	if cam.position.Y+cam.position.H > cam.bounds.Y+cam.bounds.H {
-		cam.position.Y = cam.bounds.Y + cam.bounds.H - cam.position.H
+	if view.position.Y+view.position.H > bounds.Y+bounds.H {
+		view.position.Y = bounds.Y + bounds.H - view.position.H
 	}
 }
 
@@ -43,6 +231,29 @@ func (cam *windowCamera) SetBounds(bounds Rectangle) {
 	cam.bounds = bounds
 }
 
-func (cam *windowCamera) offset() (dx, dy int) {
-	return -cam.position.X, -cam.position.Y
+// WorldToScreen returns the point in virtual screen coordinates.
+func (cam *windowCamera) WorldToScreen(x, y int) (screenX, screenY int) {
+	view := &cam.views[cam.active]
+	screenX = view.viewport.X + int(math.Floor(float64(x-view.position.X)*cam.zoom))
+	screenY = view.viewport.Y + int(math.Floor(float64(y-view.position.Y)*cam.zoom))
+	return
+}
+
+// worldToScreenRect scales the rectangle like WorldToScreen, see toWindow
+// for why the corners are scaled.
+func (cam *windowCamera) worldToScreenRect(r Rectangle) Rectangle {
+	left, top := cam.WorldToScreen(r.X, r.Y)
+	right, bottom := cam.WorldToScreen(r.X+r.W, r.Y+r.H)
+	return Rectangle{left, top, right - left, bottom - top}
+}
+
+// Center is the center of the view or, if there are two views, the point
+// between their centers.
+func (cam *windowCamera) Center() (x, y int) {
+	x, y = cam.views[0].position.Center()
+	if cam.viewCount == 2 {
+		x2, y2 := cam.views[1].position.Center()
+		x, y = (x+x2)/2, (y+y2)/2
+	}
+	return x, y
 }
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestSplitViewsHaveHysteresis(t *testing.T) {
+	cam := newWindowCamera(VirtualScreenW, VirtualScreenH)
+	for _, step := range []struct {
+		distance  int
+		viewCount int
+	}{
+		{VirtualScreenW * 4 / 10, 1},
+		{VirtualScreenW * 6 / 10, 2},
+		{VirtualScreenW * 4 / 10, 2},
+		{VirtualScreenW * 6 / 10, 2},
+		{VirtualScreenW * 3 / 10, 1},
+		{VirtualScreenW * 4 / 10, 1},
+	} {
+		cam.CenterViewsAround([]Point{{0, 0}, {step.distance, 0}})
+		if cam.ViewCount() != step.viewCount {
+			t.Errorf("players %d apart: want %d views but have %d",
+				step.distance, step.viewCount, cam.ViewCount())
+		}
+	}
+}
+
+func TestVirtualScreenFitsTheWindow(t *testing.T) {
+	for _, c := range []struct {
+		windowW, windowH int
+		integerScale     bool
+		want             Rectangle
+	}{
+		{1280, 720, false, Rectangle{0, 0, 1280, 720}},
+		{1280, 720, true, Rectangle{0, 0, 1280, 720}},
+		// wider windows have black bars left and right, higher ones on top
+		// and at the bottom
+		{1920, 1080, false, Rectangle{0, 0, 1920, 1080}},
+		{1920, 1080, true, Rectangle{320, 180, 1280, 720}},
+		{1280, 1024, false, Rectangle{0, 152, 1280, 720}},
+		{2560, 1600, false, Rectangle{0, 80, 2560, 1440}},
+		{2560, 1600, true, Rectangle{0, 80, 2560, 1440}},
+		{2000, 1440, false, Rectangle{0, 157, 2000, 1125}},
+		{2000, 1440, true, Rectangle{360, 360, 1280, 720}},
+		{3000, 720, false, Rectangle{860, 0, 1280, 720}},
+		// too small windows shrink the screen, even with integer scaling
+		{640, 480, false, Rectangle{0, 60, 640, 360}},
+		{640, 480, true, Rectangle{0, 60, 640, 360}},
+	} {
+		cam := newWindowCamera(VirtualScreenW, VirtualScreenH)
+		cam.setIntegerScale(c.integerScale)
+		cam.setWindowSize(c.windowW, c.windowH)
+		if cam.screenArea != c.want {
+			t.Errorf("%dx%d window, integer scale %v: want %v but have %v",
+				c.windowW, c.windowH, c.integerScale, c.want, cam.screenArea)
+		}
+	}
+}
+
+func TestScalingToTheWindowLeavesNoGaps(t *testing.T) {
+	cam := newWindowCamera(VirtualScreenW, VirtualScreenH)
+	cam.setWindowSize(1000, 1000)
+	// the screen is scaled by 1000/1280, 10 pixels are 7.8 window pixels
+	left := cam.toWindow(Rectangle{0, 0, 10, 10})
+	right := cam.toWindow(Rectangle{10, 0, 10, 10})
+	if left.X+left.W != right.X {
+		t.Errorf("%v and %v do not touch", left, right)
+	}
+	if right.Y != cam.screenArea.Y {
+		t.Errorf("want the rectangle at the top of the screen, %d, but have %d",
+			cam.screenArea.Y, right.Y)
+	}
+}
+
+func TestZoomChangesTheVisibleWorld(t *testing.T) {
+	for _, c := range []struct {
+		zoom float64
+		want Rectangle
+	}{
+		{1, Rectangle{360, 140, 1280, 720}},
+		{2, Rectangle{680, 320, 640, 360}},
+		{0.5, Rectangle{-280, -220, 2560, 1440}},
+	} {
+		cam := newWindowCamera(VirtualScreenW, VirtualScreenH)
+		cam.setZoom(c.zoom)
+		cam.CenterAround(1000, 500)
+		if cam.views[0].position != c.want {
+			t.Errorf("zoom %v: want the view at %v but have %v",
+				c.zoom, c.want, cam.views[0].position)
+		}
+		// the center stays in the middle of the screen
+		x, y := cam.WorldToScreen(1000, 500)
+		if x != VirtualScreenW/2 || y != VirtualScreenH/2 {
+			t.Errorf("zoom %v: the center is drawn at %d,%d", c.zoom, x, y)
+		}
+		// 100 world pixels are 100*zoom screen pixels
+		if x2, _ := cam.WorldToScreen(1100, 500); x2-x != int(100*c.zoom) {
+			t.Errorf("zoom %v: 100 pixels are %d on the screen", c.zoom, x2-x)
+		}
+	}
+}